@@ -4,9 +4,8 @@ go 1.17
 
 require (
 	github.com/iancoleman/strcase v0.2.0
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/theplant/htmlgo v1.0.1
 	github.com/theplant/testingutils v0.0.0-20190603093022-26d8b4d95c61
 	golang.org/x/net v0.0.0-20210917221730-978cfadd31cf
 )
-
-require github.com/pmezard/go-difflib v1.0.0 // indirect
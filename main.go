@@ -1,18 +1,335 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
 
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/sunfmin/html2go/parse"
 )
 
 var pkg = flag.String("pkg", "", "generated htmlgo pkg name")
 var childrenMode = flag.Bool("c", false, "children mode")
+var templateMode = flag.Bool("template", false, "input is a html/template file")
+var markdownMode = flag.Bool("markdown", false, "input is Markdown")
+var placeholderMode = flag.Bool("placeholders", false, "turn {{name}} and {% for %} placeholders into function parameters")
+var configFile = flag.String("config", "", "JSON config file")
+var target = flag.String("target", "", "generated code target: htmlgo, gomponents or templ")
+var shape = flag.String("shape", "", "output shape: var, func, method, expr or children")
+var name = flag.String("name", "", "name of the generated var, func or method")
+var receiver = flag.String("receiver", "", "receiver type of the generated method")
+var valueReceiver = flag.Bool("value-receiver", false, "make the receiver of the generated method a value instead of a pointer")
+var dropImplicit = flag.Bool("drop-implicit", false, "drop the tbody, colgroup and body elements the HTML parser inserts")
+var strict = flag.Bool("strict", false, "fail if the HTML parser has to repair the input")
+var srcComments = flag.Bool("src-comments", false, "add // src:line:column comments with the HTML position to the generated calls")
+var sourceMap = flag.String("sourcemap", "", "write the JSON source map of the generated code to this file")
+var whitespace = flag.String("whitespace", "", "text white space policy: trim, collapse or preserve")
+var into = flag.String("into", "", "Go file to put the generated code into, see -func and -region")
+var intoFunc = flag.String("func", "", "function of the -into file whose return value is replaced")
+var region = flag.String("region", "", "name of the // html2go:begin region of the -into file that is replaced")
+var watch = flag.String("watch", "", "directory whose HTML files are converted into Go files whenever they change")
+var attrOrder = flag.String("attr-order", "", "order of the attributes: source, alphabetical or canonical")
+var indent = flag.Int("indent", -1, "number of tabs to indent the children shape by")
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		lsp(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rawhtml" {
+		rawHTML(os.Args[2:])
+		return
+	}
 	flag.Parse()
 
-	fmt.Println(parse.GenerateHTMLGo(*pkg, *childrenMode, os.Stdin))
+	cfg := &parse.Config{}
+	if len(*configFile) > 0 {
+		var err error
+		cfg, err = parse.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	cfg.Warnings = os.Stderr
+
+	if len(*target) > 0 {
+		cfg.Target = *target
+	}
+	if len(*shape) > 0 {
+		cfg.Shape = *shape
+	}
+	if len(*name) > 0 {
+		cfg.Name = *name
+	}
+	if len(*receiver) > 0 {
+		cfg.Receiver = *receiver
+	}
+	if len(*whitespace) > 0 {
+		cfg.Whitespace = *whitespace
+	}
+	if len(*attrOrder) > 0 {
+		cfg.AttrOrder = *attrOrder
+	}
+	if *valueReceiver {
+		cfg.ValueReceiver = true
+	}
+	if *dropImplicit {
+		cfg.DropImplicit = true
+	}
+	if *srcComments {
+		cfg.SourceComments = true
+	}
+	if len(*sourceMap) > 0 {
+		f, err := os.Create(*sourceMap)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		cfg.SourceMap = f
+	}
+	if *indent >= 0 {
+		cfg.Indent = *indent
+	}
+
+	if len(*watch) > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		w := &parse.Watcher{
+			Converter: parse.NewConverter(parse.Options{Pkg: *pkg, ChildrenMode: *childrenMode, Config: *cfg}),
+			Dir:       *watch,
+			Log:       os.Stderr,
+		}
+		err := w.Run(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	src, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	// Markdown isn't linted as HTML
+	if !*markdownMode {
+		ds, err := parse.Lint(bytes.NewReader(src))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		for _, d := range ds {
+			fmt.Fprintf(os.Stderr, "stdin:%s\n", d)
+		}
+		if *strict && len(ds) > 0 {
+			os.Exit(1)
+		}
+	}
+
+	if len(*into) > 0 {
+		err = insertInto(cfg, src)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	c := parse.NewConverter(parse.Options{Pkg: *pkg, ChildrenMode: *childrenMode, Config: *cfg})
+	var code string
+	switch {
+	case *templateMode:
+		code, err = c.ConvertTemplate(bytes.NewReader(src))
+	case *markdownMode:
+		code, err = c.ConvertMarkdown(bytes.NewReader(src))
+	case *placeholderMode:
+		code, err = c.ConvertPlaceholders(bytes.NewReader(src))
+	default:
+		code, err = c.Convert(bytes.NewReader(src))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(code)
+}
+
+func insertInto(cfg *parse.Config, src []byte) (err error) {
+	gosrc, err := ioutil.ReadFile(*into)
+	if err != nil {
+		return
+	}
+	c := parse.NewConverter(parse.Options{Pkg: *pkg, ChildrenMode: *childrenMode, Config: *cfg})
+	var r []byte
+	switch {
+	case len(*intoFunc) > 0:
+		r, err = c.InsertFunc(gosrc, *intoFunc, bytes.NewReader(src))
+	case len(*region) > 0:
+		r, err = c.InsertRegion(gosrc, *region, bytes.NewReader(src))
+	default:
+		err = fmt.Errorf("-into needs -func or -region")
+	}
+	if err != nil {
+		return
+	}
+	return ioutil.WriteFile(*into, r, 0644)
+}
+
+// serve runs the web playground.
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	_ = fs.Parse(args)
+
+	fmt.Fprintf(os.Stderr, "html2go playground on http://%s/\n", *addr)
+	err := http.ListenAndServe(*addr, parse.Playground())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// lsp runs the language server on stdin and stdout.
+func lsp(args []string) {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	pkg := fs.String("pkg", "", "generated htmlgo pkg name")
+	childrenMode := fs.Bool("c", false, "children mode")
+	configFile := fs.String("config", "", "JSON config file")
+	_ = fs.Parse(args)
+
+	cfg := &parse.Config{}
+	if len(*configFile) > 0 {
+		var err error
+		cfg, err = parse.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	s := &parse.LanguageServer{
+		Converter: parse.NewConverter(parse.Options{Pkg: *pkg, ChildrenMode: *childrenMode, Config: *cfg}),
+	}
+	err := s.Serve(os.Stdin, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// rawHTML rewrites the RawHTML calls with constant HTML of the Go files of
+// the packages into builder code.
+func rawHTML(args []string) {
+	fs := flag.NewFlagSet("rawhtml", flag.ExitOnError)
+	dryRun := fs.Bool("diff", false, "print the diff of the files instead of rewriting them")
+	configFile := fs.String("config", "", "JSON config file")
+	whitespace := fs.String("whitespace", "", "text white space policy: trim, collapse or preserve")
+	_ = fs.Parse(args)
+
+	cfg := &parse.Config{}
+	if len(*configFile) > 0 {
+		var err error
+		cfg, err = parse.LoadConfig(*configFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	cfg.Warnings = os.Stderr
+	if len(*whitespace) > 0 {
+		cfg.Whitespace = *whitespace
+	}
+	c := parse.NewConverter(parse.Options{Config: *cfg})
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	files, err := goFiles(patterns)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		r, err := c.RewriteRawHTML(file, src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			os.Exit(1)
+		}
+		if bytes.Equal(r, src) {
+			continue
+		}
+		if *dryRun {
+			diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(string(src)),
+				B:        difflib.SplitLines(string(r)),
+				FromFile: file,
+				ToFile:   file,
+				Context:  3,
+			})
+			fmt.Print(diff)
+			continue
+		}
+		err = ioutil.WriteFile(file, r, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, file)
+	}
+}
+
+// goFiles returns the Go files of the package directories, and of all
+// directories below them for patterns ending in /..., like the go command
+// skipping testdata, vendor and hidden directories.
+func goFiles(patterns []string) (r []string, err error) {
+	for _, pattern := range patterns {
+		dir := strings.TrimSuffix(pattern, "/...")
+		recursive := dir != pattern || pattern == "..."
+		if pattern == "..." {
+			dir = "."
+		}
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path == dir {
+					return nil
+				}
+				name := info.Name()
+				if !recursive || name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(path, ".go") {
+				r = append(r, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
 }
@@ -0,0 +1,310 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxCodeSize is the size of the synthetic file the positions of the
+// generated nodes are in.
+const maxCodeSize = 1 << 30
+
+// astBuilder builds the generated code as go/ast nodes. go/printer breaks
+// lines where the positions of the nodes are on different lines, so the
+// nodes get positions in a synthetic file, laid out as if the code was
+// written out: write advances the position by the text a node takes.
+type astBuilder struct {
+	fset     *token.FileSet
+	file     *token.File
+	offset   int
+	comments []*ast.CommentGroup
+	// attrOrder is the order the attributes of elements are written in.
+	attrOrder string
+	// exactStrings is Config.exactStrings.
+	exactStrings bool
+	// err is the first Go expression from the input that doesn't parse.
+	err error
+}
+
+func newASTBuilder() *astBuilder {
+	fset := token.NewFileSet()
+	return &astBuilder{fset: fset, file: fset.AddFile("", -1, maxCodeSize)}
+}
+
+// write advances the position over s and returns the position of its
+// start.
+func (b *astBuilder) write(s string) token.Pos {
+	pos := b.file.Pos(b.offset)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' {
+			b.file.AddLine(b.offset + i + 1)
+		}
+	}
+	b.offset += len(s)
+	return pos
+}
+
+func (b *astBuilder) ident(name string) *ast.Ident {
+	return &ast.Ident{NamePos: b.write(name), Name: name}
+}
+
+// name returns the identifier or qualified identifier name, e.g. h.Div.
+func (b *astBuilder) name(name string) ast.Expr {
+	parts := strings.Split(name, ".")
+	var x ast.Expr = b.ident(parts[0])
+	for _, p := range parts[1:] {
+		b.write(".")
+		x = &ast.SelectorExpr{X: x, Sel: b.ident(p)}
+	}
+	return x
+}
+
+// call starts a call of fun, whose arguments are added by the caller before
+// closing it with end.
+func (b *astBuilder) call(fun ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{Fun: fun, Lparen: b.write("(")}
+}
+
+func (b *astBuilder) end(call *ast.CallExpr) *ast.CallExpr {
+	call.Rparen = b.write(")")
+	return call
+}
+
+// comment adds the line comment text, which starts with //.
+func (b *astBuilder) comment(text string) {
+	b.write(" ")
+	b.comments = append(b.comments, &ast.CommentGroup{List: []*ast.Comment{{Slash: b.write(text), Text: text}}})
+}
+
+// lit returns the Go literal src the converter formatted, a string, a
+// possibly negative number, true or false.
+func (b *astBuilder) lit(src string) ast.Expr {
+	switch src {
+	case "true", "false":
+		return b.ident(src)
+	}
+	if strings.HasPrefix(src, "-") {
+		return &ast.UnaryExpr{OpPos: b.write("-"), Op: token.SUB, X: b.lit(src[1:])}
+	}
+	kind := literalKind(src)
+	if kind == token.ILLEGAL {
+		if b.err == nil {
+			b.err = fmt.Errorf("invalid Go literal %q", src)
+		}
+		return &ast.BadExpr{From: b.write(src)}
+	}
+	return &ast.BasicLit{ValuePos: b.write(src), Kind: kind, Value: src}
+}
+
+// str returns the Go string literal of s.
+func (b *astBuilder) str(s string) ast.Expr {
+	return b.lit(strconv.Quote(s))
+}
+
+// literalKind returns the kind of the Go literal src, or token.ILLEGAL if src
+// is anything else.
+func literalKind(src string) token.Token {
+	var s scanner.Scanner
+	s.Init(token.NewFileSet().AddFile("", -1, len(src)), []byte(src), nil, 0)
+	_, tok, lit := s.Scan()
+	if !tok.IsLiteral() || tok == token.IDENT || lit != src || s.ErrorCount > 0 {
+		return token.ILLEGAL
+	}
+	return tok
+}
+
+// expr parses the Go expression src, which comes from the input, like the
+// conditions of templates.
+func (b *astBuilder) expr(src string) ast.Expr {
+	fset := token.NewFileSet()
+	x, err := parser.ParseExprFrom(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		if b.err == nil {
+			b.err = fmt.Errorf("invalid Go expression %q: %w", src, err)
+		}
+		return &ast.BadExpr{From: b.write(src)}
+	}
+	// move the positions from the file of src to where it is written
+	base := b.write(src) - token.Pos(fset.File(x.Pos()).Base())
+	movePositions(reflect.ValueOf(x), base)
+	return x
+}
+
+// rangeLit returns the call of a func literal returning the children of fc
+// for every item of fc.RangeExpr, or else if there are none, as r of type
+// typ.
+func (b *astBuilder) rangeLit(typ string, fc *funcCall, children func() []ast.Expr, els func() []ast.Expr) *ast.CallExpr {
+	key, value := fc.RangeKey, fc.RangeValue
+	if len(key) == 0 {
+		key = "_"
+	}
+	if len(value) == 0 {
+		value = "item"
+	}
+
+	return b.funcLit(typ, func() (r []ast.Stmt) {
+		loop := &ast.RangeStmt{For: b.write("for ")}
+		loop.Key = b.ident(key)
+		b.write(", ")
+		loop.Value = b.ident(value)
+		b.write(" ")
+		loop.Tok, loop.TokPos = token.DEFINE, b.write(":=")
+		b.write(" range ")
+		loop.X = b.expr(fc.RangeExpr)
+		loop.Body = b.block(b.appendStmt(children))
+		r = append(r, loop)
+
+		if els != nil {
+			ifs := &ast.IfStmt{If: b.write("if ")}
+			length := b.call(b.ident("len"))
+			length.Args = append(length.Args, b.ident("r"))
+			cond := &ast.BinaryExpr{X: b.end(length), Op: token.EQL}
+			b.write(" ")
+			cond.OpPos = b.write("==")
+			b.write(" ")
+			cond.Y = b.lit("0")
+			ifs.Cond = cond
+			ifs.Body = b.block(b.appendStmt(els))
+			r = append(r, ifs)
+		}
+		return
+	})
+}
+
+// ifLit returns the call of a func literal returning the children if cond
+// holds, or else els, as r of type typ. Unlike If, the children are only
+// built when cond holds.
+func (b *astBuilder) ifLit(typ string, cond string, children func() []ast.Expr, els func() []ast.Expr) *ast.CallExpr {
+	return b.funcLit(typ, func() []ast.Stmt {
+		ifs := &ast.IfStmt{If: b.write("if ")}
+		ifs.Cond = b.expr(cond)
+		ifs.Body = b.block(b.appendStmt(children))
+		if els != nil {
+			b.write("else")
+			ifs.Else = b.block(b.appendStmt(els))
+		}
+		return []ast.Stmt{ifs}
+	})
+}
+
+// funcLit returns the call of a func literal with the statements written by
+// body, returning r of type typ.
+func (b *astBuilder) funcLit(typ string, body func() []ast.Stmt) *ast.CallExpr {
+	fn := &ast.FuncLit{Type: &ast.FuncType{Func: b.write("func")}}
+	fn.Type.Params = &ast.FieldList{Opening: b.write("("), Closing: b.write(")")}
+	b.write(" ")
+	results := &ast.FieldList{Opening: b.write("(")}
+	r := b.ident("r")
+	b.write(" ")
+	results.List = []*ast.Field{{Names: []*ast.Ident{r}, Type: b.expr(typ)}}
+	results.Closing = b.write(")")
+	fn.Type.Results = results
+	b.write(" ")
+	fn.Body = &ast.BlockStmt{Lbrace: b.write("{")}
+	b.write("\n")
+	fn.Body.List = body()
+	fn.Body.List = append(fn.Body.List, &ast.ReturnStmt{Return: b.write("return")})
+	b.write("\n")
+	fn.Body.Rbrace = b.write("}")
+	return b.end(b.call(fn))
+}
+
+// block returns the block of the statement written by stmt.
+func (b *astBuilder) block(stmt func() ast.Stmt) *ast.BlockStmt {
+	b.write(" ")
+	block := &ast.BlockStmt{Lbrace: b.write("{")}
+	b.write("\n")
+	block.List = []ast.Stmt{stmt()}
+	block.Rbrace = b.write("}")
+	b.write("\n")
+	return block
+}
+
+// appendStmt returns the statement appending the children to r.
+func (b *astBuilder) appendStmt(children func() []ast.Expr) func() ast.Stmt {
+	return func() ast.Stmt {
+		r := b.ident("r")
+		b.write(" ")
+		stmt := &ast.AssignStmt{Lhs: []ast.Expr{r}, Tok: token.ASSIGN, TokPos: b.write("=")}
+		b.write(" ")
+		call := b.call(b.ident("append"))
+		call.Args = append(call.Args, b.ident("r"))
+		b.write(",\n")
+		call.Args = append(call.Args, children()...)
+		stmt.Rhs = []ast.Expr{b.end(call)}
+		b.write("\n")
+		return stmt
+	}
+}
+
+// funcDecl returns the function, or the method with a receiver of type recv
+// if it isn't empty, returning the expression written by body.
+func (b *astBuilder) funcDecl(recv string, name string, params []*funcParam, types map[string]string, result string, body func() ast.Expr) *ast.FuncDecl {
+	fd := &ast.FuncDecl{Type: &ast.FuncType{Func: b.write("func")}}
+	b.write(" ")
+	if len(recv) > 0 {
+		fd.Recv = b.paramList([]*funcParam{{Name: receiverName(recv), Type: recv}}, nil)
+		b.write(" ")
+	}
+	fd.Name = b.ident(name)
+	fd.Type.Params = b.paramList(params, types)
+	b.write(" ")
+	fd.Type.Results = &ast.FieldList{List: []*ast.Field{{Type: b.expr(result)}}}
+	b.write(" ")
+	fd.Body = &ast.BlockStmt{Lbrace: b.write("{")}
+	b.write("\n")
+	ret := &ast.ReturnStmt{Return: b.write("return")}
+	b.write(" ")
+	ret.Results = []ast.Expr{body()}
+	fd.Body.List = []ast.Stmt{ret}
+	fd.Body.Rbrace = b.write("}")
+	b.write("\n")
+	return fd
+}
+
+// varDecl returns the var declaration of name with the value written by
+// value.
+func (b *astBuilder) varDecl(name string, value func() ast.Expr) *ast.GenDecl {
+	gd := &ast.GenDecl{Tok: token.VAR, TokPos: b.write("var")}
+	b.write(" ")
+	spec := &ast.ValueSpec{Names: []*ast.Ident{b.ident(name)}}
+	b.write(" = ")
+	spec.Values = []ast.Expr{value()}
+	gd.Specs = []ast.Spec{spec}
+	return gd
+}
+
+var posType = reflect.TypeOf(token.NoPos)
+
+// movePositions adds delta to the valid positions of the node v.
+func movePositions(v reflect.Value, delta token.Pos) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			movePositions(v.Elem(), delta)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			movePositions(v.Index(i), delta)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			switch {
+			case f.Type() == posType:
+				if p := token.Pos(f.Int()); p.IsValid() {
+					f.SetInt(int64(p + delta))
+				}
+			case f.Type() == reflect.TypeOf(&ast.Object{}), f.Type() == reflect.TypeOf(&ast.Scope{}):
+			case f.CanSet():
+				movePositions(f, delta)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package parse_test
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestConvertAST(t *testing.T) {
+	c := parse.NewConverter(parse.Options{Config: parse.Config{Shape: parse.ShapeFunc, Name: "Card"}})
+	fset, f, err := c.ConvertAST(strings.NewReader(`<div class="card">
+	<h1 data-html2go-param-title="text">Hello</h1>
+	<a data-html2go-func="More" href="/more">More</a>
+</div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, d := range f.Decls {
+		names = append(names, d.(*ast.FuncDecl).Name.Name)
+	}
+	diff := testingutils.PrettyJsonDiff([]string{"Card", "More"}, names)
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+
+	// the returned expression can be printed on its own
+	ret := f.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ReturnStmt).Results[0]
+	buf := bytes.NewBuffer(nil)
+	err = format.Node(buf, fset, ret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `Body(
+	Div(
+		H1(title),
+		More(),
+	).Class("card"),
+)`
+	diff = testingutils.PrettyJsonDiff(expected, buf.String())
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+}
+
+func TestConvertInvalidExpr(t *testing.T) {
+	c := parse.NewConverter(parse.Options{})
+	_, err := c.Convert(strings.NewReader(`<template data-html2go-if="user.Admin &&"><p>Admin</p></template>`))
+	if err == nil || !strings.Contains(err.Error(), `invalid Go expression "user.Admin &&"`) {
+		t.Errorf("err: %v", err)
+	}
+}
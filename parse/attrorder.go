@@ -0,0 +1,102 @@
+package parse
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Attribute orders selectable with Config.AttrOrder.
+const (
+	// AttrOrderSource keeps the attributes in the order of the HTML, the
+	// default.
+	AttrOrderSource = "source"
+	// AttrOrderAlphabetical sorts the attributes by name.
+	AttrOrderAlphabetical = "alphabetical"
+	// AttrOrderCanonical puts id and class first, then the attributes with
+	// typed methods, the other ones, data-*, aria-* and last the event
+	// handlers, each group sorted by name.
+	AttrOrderCanonical = "canonical"
+)
+
+// Groups of the canonical attribute order.
+const (
+	attrRankID = iota
+	attrRankClass
+	attrRankTyped
+	attrRankOther
+	attrRankData
+	attrRankAria
+	attrRankEvent
+)
+
+// orderedAttrs returns the attributes of fc in the order, typed tells
+// whether the target has a method for an attribute.
+func (fc *funcCall) orderedAttrs(order string, typed func(key string) bool) []html.Attribute {
+	if err := checkAttrOrder(order); err != nil {
+		panic(err)
+	}
+	if order == AttrOrderSource || len(order) == 0 {
+		return fc.Attrs
+	}
+
+	rank := func(key string) int {
+		if order == AttrOrderAlphabetical {
+			return 0
+		}
+		return attrRank(key, typed)
+	}
+	attrs := append([]html.Attribute(nil), fc.Attrs...)
+	sort.SliceStable(attrs, func(i, j int) bool {
+		ri, rj := rank(attrs[i].Key), rank(attrs[j].Key)
+		if ri != rj {
+			return ri < rj
+		}
+		// sorted by the names written, @click is x-on:click
+		return expandAlpineKey(attrs[i].Key) < expandAlpineKey(attrs[j].Key)
+	})
+	return attrs
+}
+
+func checkAttrOrder(order string) error {
+	switch order {
+	case AttrOrderSource, AttrOrderAlphabetical, AttrOrderCanonical, "":
+		return nil
+	}
+	return fmt.Errorf("unknown attribute order %q, supported orders: %s, %s, %s",
+		order, AttrOrderSource, AttrOrderAlphabetical, AttrOrderCanonical)
+}
+
+func attrRank(key string, typed func(key string) bool) int {
+	lower := strings.ToLower(key)
+	switch {
+	case lower == "id":
+		return attrRankID
+	case lower == "class":
+		return attrRankClass
+	case isEventHandler(lower):
+		return attrRankEvent
+	case strings.HasPrefix(lower, "data-"):
+		return attrRankData
+	case strings.HasPrefix(lower, "aria-"):
+		return attrRankAria
+	case typed(key):
+		return attrRankTyped
+	}
+	return attrRankOther
+}
+
+// isEventHandler reports whether the attribute is an event handler, like
+// onclick or the Alpine.js and Vue @click, x-on:click and v-on:click.
+func isEventHandler(key string) bool {
+	return strings.HasPrefix(key, "on") && len(key) > 2 ||
+		strings.HasPrefix(key, "@") ||
+		strings.HasPrefix(key, "x-on:") ||
+		strings.HasPrefix(key, "v-on:")
+}
+
+func untyped(key string) bool {
+	return false
+}
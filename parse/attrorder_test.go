@@ -0,0 +1,107 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestAttrOrder(t *testing.T) {
+	html := `<input onclick="go()" aria-label="Search" data-id="1" type="text" id="q" x-cloak class="field" @keyup="find()">`
+	var cases = []struct {
+		name   string
+		target string
+		order  string
+		code   string
+	}{
+		{
+			name:  "source",
+			order: parse.AttrOrderSource,
+			code: `Components(
+	Input("").Attr("onclick", "go()").
+		Attr("aria-label", "Search").
+		Attr("data-id", "1").
+		Type("text").
+		Id("q").
+		Attr("x-cloak", "").
+		Class("field").
+		Attr("x-on:keyup", "find()"),
+)`,
+		},
+		{
+			name:  "alphabetical",
+			order: parse.AttrOrderAlphabetical,
+			code: `Components(
+	Input("").Attr("aria-label", "Search").
+		Class("field").
+		Attr("data-id", "1").
+		Id("q").
+		Attr("onclick", "go()").
+		Type("text").
+		Attr("x-cloak", "").
+		Attr("x-on:keyup", "find()"),
+)`,
+		},
+		{
+			name:  "canonical",
+			order: parse.AttrOrderCanonical,
+			code: `Components(
+	Input("").Id("q").
+		Class("field").
+		Type("text").
+		Attr("x-cloak", "").
+		Attr("data-id", "1").
+		Attr("aria-label", "Search").
+		Attr("onclick", "go()").
+		Attr("x-on:keyup", "find()"),
+)`,
+		},
+		{
+			name:   "canonical gomponents",
+			target: parse.TargetGomponents,
+			order:  parse.AttrOrderCanonical,
+			code: `g.Group([]g.Node{
+	Input(ID("q"), Class("field"), Type("text"), g.Attr("x-cloak"), DataAttr("id", "1"), Aria("label", "Search"), g.Attr("onclick", "go()"), g.Attr("x-on:keyup", "find()")),
+})`,
+		},
+		{
+			name:   "canonical templ",
+			target: parse.TargetTempl,
+			order:  parse.AttrOrderCanonical,
+			code: `package hello
+
+templ Component() {
+	<input id="q" class="field" type="text" x-cloak data-id="1" aria-label="Search" onclick="go()" x-on:keyup="find()"/>
+}`,
+		},
+	}
+
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			c := parse.NewConverter(parse.Options{Config: parse.Config{
+				Target:       cs.target,
+				Shape:        parse.ShapeExpr,
+				DropImplicit: true,
+				AttrOrder:    cs.order,
+			}})
+			code, err := c.Convert(strings.NewReader(html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := testingutils.PrettyJsonDiff(cs.code, strings.TrimSpace(code))
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestAttrOrderUnknown(t *testing.T) {
+	c := parse.NewConverter(parse.Options{Config: parse.Config{AttrOrder: "random"}})
+	_, err := c.Convert(strings.NewReader(`<p id="a" class="b">Hi</p>`))
+	if err == nil {
+		t.Error("no error for an unknown attribute order")
+	}
+}
@@ -0,0 +1,95 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// backend renders the funcCall tree as code of one Go HTML library.
+type backend interface {
+	// marshal writes the code of fc followed by ",\n" to b and returns its
+	// expression.
+	marshal(b *astBuilder, fc *funcCall, methodNames []string, pkg string, childrenMode bool) ast.Expr
+	// componentType is the Go type generated functions return.
+	componentType(pkg string) string
+	// imports is the import declaration of the generated code.
+	imports(pkg string) string
+	// declares reports whether the imported package declares name, which
+	// then can't be declared next to its dot import.
+	declares(name string) bool
+}
+
+// importName is the name a package is imported as for the pkg prefix,
+// dot imported without it.
+func importName(pkg string) string {
+	if len(pkg) == 0 {
+		return "."
+	}
+	return pkg
+}
+
+// Code generation targets selectable with Config.Target.
+const (
+	TargetHTMLGo     = "htmlgo"
+	TargetGomponents = "gomponents"
+)
+
+var backends = map[string]backend{
+	TargetHTMLGo:     htmlgoBackend{},
+	TargetGomponents: gomponentsBackend{},
+}
+
+// Targets returns the names of the supported code generation targets.
+func Targets() (r []string) {
+	r = append(r, TargetTempl)
+	for name := range backends {
+		r = append(r, name)
+	}
+	sort.Strings(r)
+	return
+}
+
+func getBackend(target string) backend {
+	b, err := findBackend(target)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// findBackend returns the backend of target, which may also be templ, whose
+// code isn't generated by a backend.
+func findBackend(target string) (b backend, err error) {
+	if len(target) == 0 {
+		return htmlgoBackend{}, nil
+	}
+	b, ok := backends[target]
+	if !ok && target != TargetTempl {
+		err = fmt.Errorf("unknown target %q, supported targets: %s", target, strings.Join(Targets(), ", "))
+	}
+	return
+}
+
+// htmlgoPath is the import path of htmlgo.
+const htmlgoPath = "github.com/theplant/htmlgo"
+
+// htmlgoBackend generates code for github.com/theplant/htmlgo.
+type htmlgoBackend struct{}
+
+func (htmlgoBackend) marshal(b *astBuilder, fc *funcCall, methodNames []string, pkg string, childrenMode bool) ast.Expr {
+	return fc.MarshalCode(b, methodNames, pkg, childrenMode)
+}
+
+func (htmlgoBackend) componentType(pkg string) string {
+	return pkgDot(pkg) + "HTMLComponent"
+}
+
+func (htmlgoBackend) imports(pkg string) string {
+	return fmt.Sprintf("import %s %q\n", importName(pkg), htmlgoPath)
+}
+
+func (htmlgoBackend) declares(name string) bool {
+	return strings.Contains(htmlgoNames, "|"+name+"|")
+}
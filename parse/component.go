@@ -0,0 +1,274 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Designers mark a subtree for extraction into its own Go function with
+//
+//	<div data-html2go-func="UserCard" data-html2go-param-name="text">
+//
+// data-html2go-param-<name>="text" turns the text content of the element into
+// the function parameter <name>, any other value names the attribute whose
+// value becomes the parameter, e.g. data-html2go-param-url="href".
+const (
+	annotationPrefix      = "data-html2go-"
+	annotationFunc        = annotationPrefix + "func"
+	annotationParamPrefix = annotationPrefix + "param-"
+	paramBindText         = "text"
+
+	// Control flow annotations, produced by the template front end but
+	// usable by hand as well. Their values are Go code.
+	annotationExpr = annotationPrefix + "expr"
+	annotationIf   = annotationPrefix + "if"
+	// annotationLazy builds the branches of an if only when they are taken,
+	// for conditions that guard the expressions in them.
+	annotationLazy       = annotationPrefix + "lazy"
+	annotationElse       = annotationPrefix + "else"
+	annotationRange      = annotationPrefix + "range"
+	annotationRangeKey   = annotationPrefix + "range-key"
+	annotationRangeValue = annotationPrefix + "range-value"
+)
+
+type funcParam struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+func (p *funcParam) goType() string {
+	if len(p.Type) == 0 {
+		return "string"
+	}
+	return p.Type
+}
+
+// typeIn is the type of p, unless overridden in types.
+func (p *funcParam) typeIn(types map[string]string) string {
+	if t, ok := types[p.Name]; ok {
+		return t
+	}
+	return p.goType()
+}
+
+func takeAnnotations(fc *funcCall) {
+	var attrs = fc.Attrs[:0:0]
+	var attrParams = map[string]string{}
+	for _, att := range fc.Attrs {
+		switch {
+		case att.Key == annotationFunc:
+			fc.FuncName = strcase.ToCamel(strings.TrimSpace(att.Val))
+		case att.Key == annotationExpr:
+			fc.Expr = att.Val
+		case att.Key == annotationIf:
+			fc.Cond = att.Val
+		case att.Key == annotationLazy:
+			fc.Lazy = true
+		case att.Key == annotationElse:
+			fc.IsElse = true
+		case att.Key == annotationRange:
+			fc.RangeExpr = att.Val
+		case att.Key == annotationRangeKey:
+			fc.RangeKey = att.Val
+		case att.Key == annotationRangeValue:
+			fc.RangeValue = att.Val
+		case strings.HasPrefix(att.Key, annotationParamPrefix):
+			name := strcase.ToLowerCamel(strings.TrimPrefix(att.Key, annotationParamPrefix))
+			bind := strings.ToLower(strings.TrimSpace(att.Val))
+			if bind == paramBindText {
+				fc.TextParam = name
+				continue
+			}
+			attrParams[bind] = name
+		case strings.HasPrefix(att.Key, annotationPrefix):
+		default:
+			attrs = append(attrs, att)
+		}
+	}
+	fc.Attrs = attrs
+
+	for _, att := range fc.Attrs {
+		name, ok := attrParams[att.Key]
+		if !ok {
+			continue
+		}
+		if fc.AttrExprs == nil {
+			fc.AttrExprs = map[string]string{}
+		}
+		fc.AttrExprs[att.Key] = name
+		fc.Params = append(fc.Params, &funcParam{Name: name, Value: att.Val})
+	}
+}
+
+// bindTextParam replaces the children of an element annotated with a text
+// parameter by a single text bound to that parameter.
+func bindTextParam(fc *funcCall) {
+	if len(fc.TextParam) == 0 {
+		return
+	}
+	var texts []string
+	for _, c := range fc.Children {
+		if len(c.Text) > 0 {
+			texts = append(texts, c.Text)
+		}
+	}
+	text := strings.Join(texts, " ")
+	fc.Children = []*funcCall{{Text: text, TextExpr: fc.TextParam}}
+	fc.Params = append(fc.Params, &funcParam{Name: fc.TextParam, Value: text})
+}
+
+// splitElse moves the children of an else marker into the Else branch of the
+// enclosing if or range.
+func splitElse(fc *funcCall) {
+	if len(fc.Cond) == 0 && len(fc.RangeExpr) == 0 {
+		return
+	}
+	var children []*funcCall
+	for _, c := range fc.Children {
+		if c.IsElse {
+			fc.Else = append(fc.Else, c.Children...)
+			continue
+		}
+		children = append(children, c)
+	}
+	fc.Children = children
+}
+
+func (fc *funcCall) marshalIf(b *astBuilder, methodNames []string, pkg string, childrenMode bool) ast.Expr {
+	if fc.Lazy {
+		children := func(fcs []*funcCall) func() []ast.Expr {
+			return func() (r []ast.Expr) {
+				for _, c := range fcs {
+					r = append(r, c.MarshalCode(b, methodNames, pkg, childrenMode))
+				}
+				return
+			}
+		}
+		var els func() []ast.Expr
+		if len(fc.Else) > 0 {
+			els = children(fc.Else)
+		}
+		return b.ifLit(pkgDot(pkg)+"HTMLComponents", fc.Cond, children(fc.Children), els)
+	}
+
+	call := b.call(b.name(pkgDot(pkg) + "If"))
+	call.Args = append(call.Args, b.expr(fc.Cond))
+	b.write(",\n")
+	for _, c := range fc.Children {
+		call.Args = append(call.Args, c.MarshalCode(b, methodNames, pkg, childrenMode))
+	}
+	r := b.end(call)
+	if len(fc.Else) > 0 {
+		b.write(".\n")
+		els := b.call(&ast.SelectorExpr{X: r, Sel: b.ident("Else")})
+		b.write("\n")
+		for _, c := range fc.Else {
+			els.Args = append(els.Args, c.MarshalCode(b, methodNames, pkg, childrenMode))
+		}
+		r = b.end(els)
+	}
+	return r
+}
+
+func (fc *funcCall) marshalRange(b *astBuilder, methodNames []string, pkg string, childrenMode bool) ast.Expr {
+	children := func(fcs []*funcCall) func() []ast.Expr {
+		return func() (r []ast.Expr) {
+			for _, c := range fcs {
+				r = append(r, c.MarshalCode(b, methodNames, pkg, childrenMode))
+			}
+			return
+		}
+	}
+	var els func() []ast.Expr
+	if len(fc.Else) > 0 {
+		els = children(fc.Else)
+	}
+	return b.rangeLit(pkgDot(pkg)+"HTMLComponents", fc, children(fc.Children), els)
+}
+
+// funcParams collects the parameters bound inside the function rooted at fc,
+// without descending into other extracted functions.
+func (fc *funcCall) funcParams() (r []*funcParam) {
+	seen := map[string]bool{}
+	var collect func(c *funcCall)
+	collect = func(c *funcCall) {
+		for _, p := range c.Params {
+			if seen[p.Name] {
+				continue
+			}
+			seen[p.Name] = true
+			r = append(r, p)
+		}
+		for _, ch := range c.Children {
+			if len(ch.FuncName) > 0 {
+				continue
+			}
+			collect(ch)
+		}
+	}
+	collect(fc)
+	return
+}
+
+// paramList returns params as a Go parameter list, with types overridden
+// by types.
+func (b *astBuilder) paramList(params []*funcParam, types map[string]string) (r *ast.FieldList) {
+	r = &ast.FieldList{Opening: b.write("(")}
+	for i, p := range params {
+		if i > 0 {
+			b.write(", ")
+		}
+		name := b.ident(p.Name)
+		b.write(" ")
+		r.List = append(r.List, &ast.Field{Names: []*ast.Ident{name}, Type: b.expr(p.typeIn(types))})
+	}
+	r.Closing = b.write(")")
+	return
+}
+
+// funcArgs are the arguments of the call of the function extracted at fc.
+func (fc *funcCall) funcArgs() (r []string) {
+	for _, p := range fc.funcParams() {
+		r = append(r, fmt.Sprintf("%#+v", p.Value))
+	}
+	return
+}
+
+func (fc *funcCall) funcCallCode(b *astBuilder) ast.Expr {
+	call := b.call(b.name(fc.FuncName))
+	for i, p := range fc.funcParams() {
+		if i > 0 {
+			b.write(", ")
+		}
+		call.Args = append(call.Args, b.str(p.Value))
+	}
+	return b.end(call)
+}
+
+func marshalComponentFuncs(b *astBuilder, root *funcCall, bk backend, methodNames []string, pkg string, childrenMode bool) (r []ast.Decl) {
+	done := map[string]bool{}
+
+	var each func(fc *funcCall)
+	each = func(fc *funcCall) {
+		if len(fc.FuncName) > 0 && !done[fc.FuncName] {
+			done[fc.FuncName] = true
+
+			body := *fc
+			body.FuncName = ""
+			b.write("\n")
+			r = append(r, b.funcDecl("", fc.FuncName, fc.funcParams(), nil, bk.componentType(pkg), func() ast.Expr {
+				return bk.marshal(b, &body, methodNames, pkg, childrenMode)
+			}))
+		}
+		for _, c := range fc.Children {
+			each(c)
+		}
+	}
+	each(root)
+
+	return
+}
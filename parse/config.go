@@ -0,0 +1,97 @@
+package parse
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+)
+
+// Config customizes the generated code. It is loaded from a JSON file with
+// LoadConfig.
+type Config struct {
+	// Target is the Go HTML library code is generated for, TargetHTMLGo
+	// if empty.
+	Target string `json:"target"`
+	// Shape of the generated code, one of ShapeVar, ShapeFunc, ShapeMethod
+	// or ShapeExpr.
+	Shape string `json:"shape"`
+	// Name of the generated var, func or method.
+	Name string `json:"name"`
+	// Whitespace is the policy for the text between elements, one of
+	// WhitespaceTrim, WhitespaceCollapse or WhitespacePreserve.
+	Whitespace string `json:"whitespace"`
+	// AttrOrder is the order of the attributes of an element, one of
+	// AttrOrderSource, AttrOrderAlphabetical or AttrOrderCanonical.
+	AttrOrder string `json:"attrOrder"`
+	// Indent is the number of tabs the children shape is indented by.
+	Indent int `json:"indent"`
+	// Receiver type of the generated method, a pointer to it unless it's
+	// written as one or ValueReceiver is set.
+	Receiver string `json:"receiver"`
+	// ValueReceiver makes the receiver of the generated method a value.
+	ValueReceiver bool `json:"valueReceiver"`
+	// Params overrides the Go type of function parameters by name.
+	Params map[string]string `json:"params"`
+	// Tags maps tag names to builders, e.g. v-btn to vuetify.VBtn.
+	Tags map[string]*TagMapping `json:"tags"`
+	// Attrs maps attribute names to builder methods for all tags.
+	Attrs map[string]string `json:"attrs"`
+	// Inputs maps input types to helpers taking the input name, e.g.
+	// checkbox to ui.Checkbox generates ui.Checkbox("agree") for
+	// <input type="checkbox" name="agree">.
+	Inputs map[string]string `json:"inputs"`
+	// Packages are directories of Go packages whose builders are added to
+	// Tags, see discoverTagMappings.
+	Packages []string `json:"packages"`
+	// CacheDir stores the builders discovered in Packages, html2go in the
+	// user cache directory if empty.
+	CacheDir string `json:"cacheDir"`
+	// DropImplicit removes the tbody and colgroup elements the HTML parser
+	// inserts into tables without them in the source, and generates a list
+	// of components instead of a Body for snippets without a <body> tag.
+	DropImplicit bool `json:"dropImplicit"`
+	// SourceComments adds a // src:line:column comment with the position
+	// in the HTML to the generated calls.
+	SourceComments bool `json:"sourceComments"`
+	// SourceMap receives the JSON array of SourcePosition of the generated
+	// code, whose source comments are removed unless SourceComments is set.
+	SourceMap io.Writer `json:"-"`
+	// Warnings receives the warnings of the conversion, discarded if nil.
+	Warnings io.Writer `json:"-"`
+
+	// exactStrings writes attribute values as they are, instead of the
+	// more readable normalizeGoString, for code that must render the same
+	// HTML.
+	exactStrings bool
+	// component generates the element of the HTML instead of a body around
+	// it, or a list of components if there are several.
+	component bool
+}
+
+// TagMapping is the builder generated for a tag.
+type TagMapping struct {
+	// Func is the builder function, qualified by its package if it is not
+	// the generated one, e.g. vuetify.VBtn.
+	Func string `json:"func"`
+	// Text is set when the first argument of Func is the text content
+	// instead of the children.
+	Text bool `json:"text"`
+	// Attrs maps attribute names to builder methods, e.g. color to Color.
+	Attrs map[string]string `json:"attrs"`
+	// Types are the Go types of the method arguments by attribute name,
+	// values are passed as strings if missing.
+	Types map[string]string `json:"types"`
+	// NoAttr is set when the builder has no Attr(key, value) method, the
+	// attributes without a method in Attrs are then dropped with a warning.
+	NoAttr bool `json:"noAttr"`
+}
+
+func LoadConfig(path string) (c *Config, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	c = &Config{}
+	err = json.Unmarshal(b, c)
+	return
+}
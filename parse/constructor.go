@@ -0,0 +1,56 @@
+package parse
+
+// constructorArgs are the attributes htmlgo constructors take as their
+// argument. These tags are in textTags, but their argument is never the
+// text, which goes to Children if any.
+var constructorArgs = map[string]string{
+	"img":    "src",
+	"input":  "name",
+	"link":   "href",
+	"object": "data",
+	"param":  "name",
+	"source": "src",
+	"time":   "datetime",
+	"track":  "src",
+}
+
+// applyConstructorArg moves the attribute the htmlgo constructor of fc takes
+// into its argument, and builds inputs with the helper configured for their
+// type in inputs, if any. The argument is exactly the attribute value if
+// exact is set.
+func applyConstructorArg(fc *funcCall, inputs map[string]string, exact bool) {
+	if key, ok := constructorArgs[fc.Tag]; ok {
+		fc.Arg = `""`
+		for _, att := range fc.Attrs {
+			if att.Key != key {
+				continue
+			}
+			fc.Arg = goString(att.Val, exact)
+			if expr, ok := fc.AttrExprs[att.Key]; ok {
+				fc.ArgExpr = expr
+			}
+			fc.Consumed = append(fc.Consumed, key)
+		}
+	}
+
+	if fc.Tag != "input" {
+		return
+	}
+	for _, att := range fc.Attrs {
+		helper, ok := inputs[att.Val]
+		if att.Key != "type" || !ok {
+			continue
+		}
+		fc.Func = helper
+		fc.Consumed = append(fc.Consumed, "type")
+	}
+}
+
+func (fc *funcCall) consumed(key string) bool {
+	for _, k := range fc.Consumed {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
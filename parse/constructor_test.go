@@ -0,0 +1,76 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestInputConstructor(t *testing.T) {
+	html := `
+<form action="/signup" method="post">
+  <input type="email" name="email" placeholder="Email" required>
+  <input type="checkbox" name="agree" value="yes" checked>
+  <input type="submit" value="Sign up">
+</form>
+`
+	var cases = []struct {
+		name   string
+		config *parse.Config
+		gocode string
+	}{
+		{
+			name: "name as argument",
+			gocode: `package hello
+
+var n = Body(
+	Form(
+		Input("email").Type("email").
+			Placeholder("Email").
+			Required(true),
+		Input("agree").Type("checkbox").
+			Value("yes").
+			Checked(true),
+		Input("").Type("submit").
+			Value("Sign up"),
+	).Action("/signup").
+		Method("post"),
+)
+`,
+		},
+		{
+			name: "specialized helpers",
+			config: &parse.Config{Inputs: map[string]string{
+				"checkbox": "ui.Checkbox",
+				"submit":   "ui.Submit",
+			}},
+			gocode: `package hello
+
+var n = Body(
+	Form(
+		Input("email").Type("email").
+			Placeholder("Email").
+			Required(true),
+		ui.Checkbox("agree").Value("yes").
+			Checked(true),
+		ui.Submit("").Value("Sign up"),
+	).Action("/signup").
+		Method("post"),
+)
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gocode := parse.GenerateHTMLGoWithConfig("", false, c.config, strings.NewReader(html))
+			diff := testingutils.PrettyJsonDiff(c.gocode, gocode)
+
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
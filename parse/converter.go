@@ -0,0 +1,171 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+)
+
+// Whitespace policies of the text between elements.
+const (
+	// WhitespaceTrim trims the text and drops the text that is only white
+	// space, the default.
+	WhitespaceTrim = "trim"
+	// WhitespaceCollapse collapses runs of white space into a single space
+	// and drops the white space text that contains a line break, except in
+	// <pre> and <textarea>, which keep their text.
+	WhitespaceCollapse = "collapse"
+	// WhitespacePreserve keeps the text as it is.
+	WhitespacePreserve = "preserve"
+)
+
+// Options configure a Converter.
+type Options struct {
+	// Pkg is the package prefix of the generated calls, none if empty.
+	Pkg string
+	// ChildrenMode generates the children of elements with Children(...).
+	ChildrenMode bool
+	// Config sets the target, output shape, whitespace policy and the
+	// other options also loaded from the JSON config.
+	Config
+}
+
+// Converter converts HTML into Go code. It can be reused for any number of
+// conversions, but not concurrently if Options has a Warnings or SourceMap
+// writer that isn't safe for concurrent use.
+type Converter struct {
+	opts        Options
+	methodNames []string
+}
+
+func NewConverter(opts Options) *Converter {
+	return &Converter{opts: opts, methodNames: tagMethodNames()}
+}
+
+func newConverter(pkg string, childrenMode bool, cfg *Config) *Converter {
+	opts := Options{Pkg: pkg, ChildrenMode: childrenMode}
+	if cfg != nil {
+		opts.Config = *cfg
+	}
+	return NewConverter(opts)
+}
+
+// Convert is GenerateHTMLGoWithConfig with the options of c.
+func (c *Converter) Convert(htmlCode io.Reader) (r string, err error) {
+	fc, src, err := c.parse(htmlCode)
+	if err != nil {
+		return
+	}
+	cfg := c.opts.Config
+	return generateWithSourceMap(fc, src, c.methodNames, c.opts.Pkg, c.opts.ChildrenMode, &cfg, ShapeVar)
+}
+
+// ConvertAST is Convert returning the generated code as a file of package
+// hello instead of printing it. Its first declaration is the component in
+// the func or method shape, or the var n otherwise, followed by the
+// extracted component functions. The positions of the nodes lay them out
+// for go/printer and are only valid with fset.
+func (c *Converter) ConvertAST(htmlCode io.Reader) (fset *token.FileSet, f *ast.File, err error) {
+	fc, src, err := c.parse(htmlCode)
+	if err != nil {
+		return
+	}
+	cfg := c.opts.Config
+	if cfg.Target == TargetTempl {
+		err = fmt.Errorf("target %s doesn't generate Go code", cfg.Target)
+		return
+	}
+	if cfg.SourceComments {
+		setPositions(fc, src)
+	}
+	if err = applyTagMappings(fc, &cfg); err != nil {
+		return
+	}
+
+	shape := cfg.Shape
+	if shape != ShapeFunc && shape != ShapeMethod {
+		shape = ShapeVar
+	}
+	return generateFile(fc, c.methodNames, c.opts.Pkg, c.opts.ChildrenMode, &cfg, shape)
+}
+
+// validate returns the error of the first option of c with an unknown value.
+func (c *Converter) validate() (err error) {
+	if _, err = findBackend(c.opts.Target); err != nil {
+		return
+	}
+	if err = checkWhitespace(c.opts.Whitespace); err != nil {
+		return
+	}
+	return checkAttrOrder(c.opts.AttrOrder)
+}
+
+// parse returns the funcCall tree of the HTML and its source.
+func (c *Converter) parse(htmlCode io.Reader) (fc *funcCall, src []byte, err error) {
+	if err = c.validate(); err != nil {
+		return
+	}
+	src, err = ioutil.ReadAll(htmlCode)
+	if err != nil {
+		return
+	}
+	body, err := parseBody(bytes.NewReader(src))
+	if err != nil {
+		return
+	}
+	fc = convert(body, c.methodNames, nil, c.opts.Whitespace)
+	st := scanSource(src, c.opts.Warnings)
+	if c.opts.DropImplicit {
+		dropImplicit(fc, st)
+	}
+	return
+}
+
+func mustGenerate(code string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return code
+}
+
+// whitespaceText returns the text of a text node under the whitespace
+// policy, empty if the node is dropped.
+func whitespaceText(text string, whitespace string) string {
+	switch whitespace {
+	case WhitespacePreserve:
+		return text
+	case WhitespaceCollapse:
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			if strings.Contains(text, "\n") {
+				return ""
+			}
+			return " "
+		}
+		r := strings.Join(fields, " ")
+		if strings.TrimLeftFunc(text, unicode.IsSpace) != text {
+			r = " " + r
+		}
+		if strings.TrimRightFunc(text, unicode.IsSpace) != text {
+			r = r + " "
+		}
+		return r
+	case WhitespaceTrim, "":
+		return strings.TrimSpace(text)
+	}
+	panic(checkWhitespace(whitespace))
+}
+
+func checkWhitespace(whitespace string) error {
+	switch whitespace {
+	case WhitespaceTrim, WhitespaceCollapse, WhitespacePreserve, "":
+		return nil
+	}
+	return fmt.Errorf("unknown whitespace policy %q, supported policies: %s, %s, %s",
+		whitespace, WhitespaceTrim, WhitespaceCollapse, WhitespacePreserve)
+}
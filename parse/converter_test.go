@@ -0,0 +1,198 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestConverter(t *testing.T) {
+	c := parse.NewConverter(parse.Options{
+		Pkg:    "h",
+		Config: parse.Config{Target: parse.TargetGomponents, Shape: parse.ShapeExpr},
+	})
+
+	cases := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			name: "first conversion",
+			html: `<p>Hello</p>`,
+			expected: `h.Body(
+	h.P(g.Text("Hello")),
+)
+`,
+		},
+		{
+			name: "reused",
+			html: `<a href="/">Home</a>`,
+			expected: `h.Body(
+	h.A(h.Href("/"), g.Text("Home")),
+)
+`,
+		},
+	}
+
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			gocode, err := c.Convert(strings.NewReader(cs.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := testingutils.PrettyJsonDiff(cs.expected, gocode)
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestConverterWhitespace(t *testing.T) {
+	html := `<p>
+  Hello,   <b>world</b> <i>again</i>
+</p>`
+
+	cases := []struct {
+		whitespace string
+		expected   string
+	}{
+		{
+			whitespace: parse.WhitespaceTrim,
+			expected: `Body(
+	P(
+		Text("Hello,"),
+		B("world"),
+		I("again"),
+	),
+)
+`,
+		},
+		{
+			whitespace: parse.WhitespaceCollapse,
+			expected: `Body(
+	P(
+		Text(" Hello, "),
+		B("world"),
+		Text(" "),
+		I("again"),
+	),
+)
+`,
+		},
+		{
+			whitespace: parse.WhitespacePreserve,
+			expected: `Body(
+	P(
+		Text("\n  Hello,   "),
+		B("world"),
+		Text(" "),
+		I("again"),
+		Text("\n"),
+	),
+)
+`,
+		},
+	}
+
+	for _, cs := range cases {
+		t.Run(cs.whitespace, func(t *testing.T) {
+			c := parse.NewConverter(parse.Options{Config: parse.Config{Shape: parse.ShapeExpr, Whitespace: cs.whitespace}})
+			gocode, err := c.Convert(strings.NewReader(html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := testingutils.PrettyJsonDiff(cs.expected, gocode)
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestConverterWhitespacePre(t *testing.T) {
+	html := `<pre>if ok {
+  <b>go</b>(  1 )
+}</pre><textarea>  a
+  b</textarea>`
+	expected := `Body(
+	Pre("").
+		Children(
+			Text("if ok {\n  "),
+			B("go"),
+			Text("(  1 )\n}"),
+		),
+	Textarea("  a\n  b"),
+)
+`
+	c := parse.NewConverter(parse.Options{Config: parse.Config{Shape: parse.ShapeExpr, Whitespace: parse.WhitespaceCollapse}})
+	gocode, err := c.Convert(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := testingutils.PrettyJsonDiff(expected, gocode)
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+}
+
+func TestConverterTemplateError(t *testing.T) {
+	c := parse.NewConverter(parse.Options{})
+	_, err := c.ConvertTemplate(strings.NewReader(`<p>{{if .Ok}}</p>`))
+	if err == nil {
+		t.Error("expected an error for the unclosed {{if}}")
+	}
+}
+
+func TestConverterOptionErrors(t *testing.T) {
+	configs := map[string]parse.Config{
+		"whitespace": {Whitespace: "squash"},
+		"target":     {Target: "react"},
+		"attr order": {AttrOrder: "random"},
+		"packages":   {Packages: []string{"testdata/missing"}, CacheDir: t.TempDir()},
+	}
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			c := parse.NewConverter(parse.Options{Config: cfg})
+			convert := map[string]func() error{
+				"Convert": func() error {
+					_, err := c.Convert(strings.NewReader(`<p>Hi</p>`))
+					return err
+				},
+				"ConvertAST": func() error {
+					_, _, err := c.ConvertAST(strings.NewReader(`<p>Hi</p>`))
+					return err
+				},
+				"ConvertTemplate": func() error {
+					_, err := c.ConvertTemplate(strings.NewReader(`<p>{{.Hi}}</p>`))
+					return err
+				},
+				"ConvertPlaceholders": func() error {
+					_, err := c.ConvertPlaceholders(strings.NewReader(`<p>{{hi}}</p>`))
+					return err
+				},
+				"ConvertMarkdown": func() error {
+					_, err := c.ConvertMarkdown(strings.NewReader(`Hi`))
+					return err
+				},
+			}
+			for fn, f := range convert {
+				if err := f(); err == nil {
+					t.Errorf("%s: no error", fn)
+				}
+			}
+		})
+	}
+
+	code, err := parse.NewConverter(parse.Options{Config: parse.Config{Shape: parse.ShapeExpr}}).
+		ConvertTemplate(strings.NewReader(`<table><tr><td colspan="{{.Span}}">x</td></tr></table>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(code, `Attr("colspan", data.Span)`) {
+		t.Errorf("colspan expression: %s", code)
+	}
+}
@@ -0,0 +1,203 @@
+package parse
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// discoverTagMappings finds builders in the Go source of cfg.Packages and
+// returns them as tag mappings merged with cfg.Tags, which take precedence.
+// A builder is an exported function returning a type whose methods return
+// the type itself, VBtn(text string) *VBtnBuilder becomes the mapping of
+// v-btn, and its method Dense(bool) the mapping of the dense attribute.
+func discoverTagMappings(cfg *Config) (r map[string]*TagMapping, err error) {
+	r = map[string]*TagMapping{}
+	for _, dir := range cfg.Packages {
+		tags, derr := cachedPackageBuilders(dir, cfg.CacheDir)
+		if derr != nil {
+			return nil, derr
+		}
+		for tag, m := range tags {
+			r[tag] = m
+		}
+	}
+	for tag, m := range cfg.Tags {
+		r[tag] = m
+	}
+	return
+}
+
+func cachedPackageBuilders(dir string, cacheDir string) (r map[string]*TagMapping, err error) {
+	key, err := packageCacheKey(dir)
+	if err != nil {
+		return
+	}
+	if len(cacheDir) == 0 {
+		cacheDir, err = os.UserCacheDir()
+		if err != nil {
+			return
+		}
+		cacheDir = filepath.Join(cacheDir, "html2go")
+	}
+	cacheFile := filepath.Join(cacheDir, key+".json")
+
+	if b, rerr := ioutil.ReadFile(cacheFile); rerr == nil {
+		if json.Unmarshal(b, &r) == nil {
+			return
+		}
+	}
+
+	r, err = packageBuilders(dir)
+	if err != nil {
+		return
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	if err = os.MkdirAll(cacheDir, 0755); err != nil {
+		return
+	}
+	err = ioutil.WriteFile(cacheFile, b, 0644)
+	return
+}
+
+// builderCacheVersion changes the cache keys when the discovered mappings
+// change.
+const builderCacheVersion = 2
+
+// packageCacheKey changes whenever a Go file of dir is added, removed or
+// modified.
+func packageCacheKey(dir string) (key string, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	_, _ = fmt.Fprintln(h, builderCacheVersion, dir)
+	for _, f := range files {
+		fi, serr := os.Stat(f)
+		if serr != nil {
+			return "", serr
+		}
+		_, _ = fmt.Fprintln(h, f, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:32], nil
+}
+
+// emptyImporter makes the type checker treat imported packages as empty, the
+// builders and their methods are declared in the package itself, so missing
+// dependencies only turn some parameter types invalid.
+type emptyImporter struct{}
+
+func (emptyImporter) Import(path string) (*types.Package, error) {
+	pkg := types.NewPackage(path, filepath.Base(path))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+func packageBuilders(dir string) (r map[string]*TagMapping, err error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return
+	}
+
+	r = map[string]*TagMapping{}
+	for name, p := range pkgs {
+		var files []*ast.File
+		for _, f := range p.Files {
+			files = append(files, f)
+		}
+		conf := types.Config{Importer: emptyImporter{}, Error: func(error) {}}
+		pkg, _ := conf.Check(name, fset, files, nil)
+
+		scope := pkg.Scope()
+		for _, objName := range scope.Names() {
+			fn, ok := scope.Lookup(objName).(*types.Func)
+			if !ok || !fn.Exported() {
+				continue
+			}
+			m := builderMapping(fn)
+			if m == nil {
+				continue
+			}
+			m.Func = name + "." + fn.Name()
+			r[strcase.ToKebab(fn.Name())] = m
+		}
+	}
+	return
+}
+
+func builderMapping(fn *types.Func) (m *TagMapping) {
+	sig := fn.Type().(*types.Signature)
+	if sig.Results().Len() != 1 {
+		return
+	}
+	builder := sig.Results().At(0).Type()
+
+	m = &TagMapping{Attrs: map[string]string{}, Types: map[string]string{}}
+	params := sig.Params()
+	if params.Len() == 1 && !sig.Variadic() && isString(params.At(0).Type()) {
+		m.Text = true
+	}
+
+	m.NoAttr = true
+	mset := types.NewMethodSet(builder)
+	for i := 0; i < mset.Len(); i++ {
+		method := mset.At(i).Obj().(*types.Func)
+		msig := method.Type().(*types.Signature)
+		if !method.Exported() || msig.Results().Len() != 1 || !types.Identical(msig.Results().At(0).Type(), builder) {
+			continue
+		}
+		// Attr(key, value string) or Attr(vs ...interface{})
+		if method.Name() == "Attr" && (msig.Params().Len() == 2 || msig.Variadic()) {
+			m.NoAttr = false
+			continue
+		}
+		// Class(names ...string) takes a single value as well
+		if msig.Params().Len() != 1 || msig.Variadic() && !isStringSlice(msig.Params().At(0).Type()) {
+			continue
+		}
+		attr := strcase.ToKebab(method.Name())
+		m.Attrs[attr] = method.Name()
+		if basic, ok := msig.Params().At(0).Type().Underlying().(*types.Basic); ok {
+			m.Types[attr] = basic.Name()
+		}
+	}
+	if len(m.Attrs) == 0 {
+		return nil
+	}
+	return
+}
+
+func isStringSlice(t types.Type) bool {
+	slice, ok := t.(*types.Slice)
+	return ok && isString(slice.Elem())
+}
+
+func isString(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Kind() == types.String
+}
@@ -0,0 +1,99 @@
+package parse_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+const vuetifySource = `package vuetify
+
+import h "github.com/theplant/htmlgo"
+
+type VBtnBuilder struct {
+	tag *h.HTMLTagBuilder
+}
+
+func VBtn(text string) (r *VBtnBuilder) {
+	return &VBtnBuilder{tag: h.Tag("v-btn").Text(text)}
+}
+
+func (b *VBtnBuilder) Color(v string) (r *VBtnBuilder) { return b }
+func (b *VBtnBuilder) Dense(v bool) (r *VBtnBuilder)   { return b }
+func (b *VBtnBuilder) Elevation(v int) (r *VBtnBuilder) { return b }
+func (b *VBtnBuilder) Class(names ...string) (r *VBtnBuilder) { return b }
+func (b *VBtnBuilder) Attr(vs ...interface{}) (r *VBtnBuilder) { return b }
+
+type VCardBuilder struct {
+	tag *h.HTMLTagBuilder
+}
+
+func VCard(children ...h.HTMLComponent) (r *VCardBuilder) {
+	return &VCardBuilder{tag: h.Tag("v-card").Children(children...)}
+}
+
+func (b *VCardBuilder) Outlined(v bool) (r *VCardBuilder) { return b }
+`
+
+func TestDiscoverBuilders(t *testing.T) {
+	pkgDir := t.TempDir()
+	cacheDir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(pkgDir, "vuetify.go"), []byte(vuetifySource), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := `
+<v-card outlined id="card">
+  <v-btn color="primary" dense elevation="2" class="ma-2" id="save">Save</v-btn>
+</v-card>
+`
+	expected := `package hello
+
+var n = Body(
+	vuetify.VCard(
+		vuetify.VBtn("Save").Color("primary").
+			Dense(true).
+			Elevation(2).
+			Class("ma-2").
+			Attr("id", "save"),
+	).Outlined(true),
+)
+`
+	for _, run := range []string{"discover", "cached"} {
+		warnings := bytes.NewBuffer(nil)
+		cfg := &parse.Config{Packages: []string{pkgDir}, CacheDir: cacheDir, Warnings: warnings}
+		gocode := parse.GenerateHTMLGoWithConfig("", false, cfg, strings.NewReader(html))
+		diff := testingutils.PrettyJsonDiff(expected, gocode)
+		if len(diff) > 0 {
+			t.Error(run, diff)
+		}
+		diff = testingutils.PrettyJsonDiff("warning: vuetify.VCard has no method for the id attribute and no Attr method, dropped it\n", warnings.String())
+		if len(diff) > 0 {
+			t.Error(run, diff)
+		}
+
+		cached, _ := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+		if len(cached) != 1 {
+			t.Errorf("%s: expected one cache file, got %v", run, cached)
+		}
+	}
+}
+
+func TestDiscoverBuildersError(t *testing.T) {
+	pkgDir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(pkgDir, "broken.go"), []byte("package vuetify\n\nfunc VBtn( {\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := parse.NewConverter(parse.Options{Config: parse.Config{Packages: []string{pkgDir}, CacheDir: t.TempDir()}})
+	_, err = c.Convert(strings.NewReader(`<v-btn>Save</v-btn>`))
+	if err == nil {
+		t.Error("no error for a package that doesn't parse")
+	}
+}
@@ -0,0 +1,103 @@
+//go:build ignore
+// +build ignore
+
+// gen_htmlgo_funcs writes htmlgo_funcs.go with the element functions declared
+// in elements.go of the htmlgo version the module requires, and all the
+// names the package exports.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var output = flag.String("o", "htmlgo_funcs.go", "file to write")
+
+func main() {
+	flag.Parse()
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "github.com/theplant/htmlgo").Output()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir := strings.TrimSpace(string(out))
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var funcs, names []string
+	for _, pkg := range pkgs {
+		for path, f := range pkg.Files {
+			for _, d := range f.Decls {
+				switch d := d.(type) {
+				case *ast.FuncDecl:
+					if d.Recv != nil || !d.Name.IsExported() {
+						continue
+					}
+					names = append(names, d.Name.Name)
+					if filepath.Base(path) == "elements.go" {
+						funcs = append(funcs, d.Name.Name)
+					}
+				case *ast.GenDecl:
+					for _, spec := range d.Specs {
+						switch spec := spec.(type) {
+						case *ast.TypeSpec:
+							if spec.Name.IsExported() {
+								names = append(names, spec.Name.Name)
+							}
+						case *ast.ValueSpec:
+							for _, n := range spec.Names {
+								if n.IsExported() {
+									names = append(names, n.Name)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("// Code generated by gen_htmlgo_funcs.go. DO NOT EDIT.\n\npackage parse\n")
+	writeList(buf, "htmlgoFuncs", "the element functions of htmlgo", funcs)
+	writeList(buf, "htmlgoNames", "all the names htmlgo exports", names)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = ioutil.WriteFile(*output, src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeList(buf *bytes.Buffer, name string, doc string, names []string) {
+	sort.Strings(names)
+	fmt.Fprintf(buf, "\n// %s are %s.\nconst %s = \"|\" +\n", name, doc, name)
+	line := ""
+	for _, name := range names {
+		if len(line)+len(name) > 90 {
+			fmt.Fprintf(buf, "\t%q +\n", line)
+			line = ""
+		}
+		line += name + "|"
+	}
+	fmt.Fprintf(buf, "\t%q\n", line)
+}
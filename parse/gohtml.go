@@ -0,0 +1,301 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// GoToHTML converts the htmlgo expression code back into HTML, the reverse
+// of the generated code. The expression may only build elements with
+// literal values, like Div(H1("Hi")).Class("title"); the package prefix of
+// the calls doesn't matter.
+func GoToHTML(code string) (r string, err error) {
+	fset := token.NewFileSet()
+	x, err := parser.ParseExprFrom(fset, "", code, parser.SkipObjectResolution)
+	if err != nil {
+		return
+	}
+	g := &goHTML{fset: fset}
+	nodes := g.nodes(x)
+	if g.err != nil {
+		return "", g.err
+	}
+	buf := bytes.NewBuffer(nil)
+	for _, n := range nodes {
+		renderIndented(buf, n, 0)
+	}
+	r = buf.String()
+	return
+}
+
+// goHTML builds the HTML nodes of an htmlgo expression.
+type goHTML struct {
+	fset *token.FileSet
+	// err is the first part of the expression that can't be converted.
+	err error
+}
+
+func (g *goHTML) fail(n ast.Node, format string, a ...interface{}) {
+	if g.err == nil {
+		g.err = fmt.Errorf("%s: %s", g.fset.Position(n.Pos()), fmt.Sprintf(format, a...))
+	}
+}
+
+// nodes returns the nodes of the component x.
+func (g *goHTML) nodes(x ast.Expr) (r []*html.Node) {
+	switch x := x.(type) {
+	case *ast.ParenExpr:
+		return g.nodes(x.X)
+	case *ast.CompositeLit:
+		if name := funcName(x.Type); name != "HTMLComponents" {
+			g.fail(x, "can't convert a %s literal", name)
+			return
+		}
+		for _, el := range x.Elts {
+			r = append(r, g.nodes(el)...)
+		}
+		return
+	case *ast.CallExpr:
+		if x.Ellipsis.IsValid() {
+			g.fail(x, "can't convert the variadic arguments of %s", funcName(x.Fun))
+			return
+		}
+		if sel, ok := x.Fun.(*ast.SelectorExpr); ok {
+			if _, ok := sel.X.(*ast.Ident); !ok {
+				return g.method(x, sel)
+			}
+		}
+		return g.call(x, funcName(x.Fun))
+	}
+	g.fail(x, "can't convert %T, only calls with literal arguments", x)
+	return
+}
+
+// call returns the nodes of the call of the htmlgo function name.
+func (g *goHTML) call(x *ast.CallExpr, name string) (r []*html.Node) {
+	switch name {
+	case "Text", "RawHTML":
+		if len(x.Args) != 1 {
+			g.fail(x, "%s needs one argument", name)
+			return
+		}
+		n := &html.Node{Type: html.TextNode, Data: g.str(x.Args[0])}
+		if name == "RawHTML" {
+			n.Type = html.RawNode
+		}
+		return []*html.Node{n}
+	case "Components", "HTML":
+		for _, arg := range x.Args {
+			r = append(r, g.nodes(arg)...)
+		}
+		return
+	case "Tag":
+		if len(x.Args) != 1 {
+			g.fail(x, "Tag needs one argument")
+			return
+		}
+		return []*html.Node{{Type: html.ElementNode, Data: g.str(x.Args[0])}}
+	}
+
+	tag := strings.ToLower(name)
+	if atom.Lookup([]byte(tag)) == 0 {
+		g.fail(x, "%s isn't an htmlgo element function", name)
+		return
+	}
+	n := &html.Node{Type: html.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag))}
+	switch {
+	case len(constructorArgs[tag]) > 0 && len(x.Args) == 1:
+		if v := g.str(x.Args[0]); len(v) > 0 {
+			n.Attr = append(n.Attr, html.Attribute{Key: constructorArgs[tag], Val: v})
+		}
+	case strings.Contains(textTags, "|"+name+"|") && len(x.Args) == 1:
+		if v := g.str(x.Args[0]); len(v) > 0 {
+			n.AppendChild(&html.Node{Type: html.TextNode, Data: v})
+		}
+	default:
+		for _, arg := range x.Args {
+			appendChildren(n, g.nodes(arg))
+		}
+	}
+	return []*html.Node{n}
+}
+
+// method applies the method call x of the element built by sel.X.
+func (g *goHTML) method(x *ast.CallExpr, sel *ast.SelectorExpr) (r []*html.Node) {
+	r = g.nodes(sel.X)
+	if g.err != nil {
+		return
+	}
+	if len(r) != 1 || r[0].Type != html.ElementNode {
+		g.fail(x, "%s isn't a method of an element", sel.Sel.Name)
+		return
+	}
+	n := r[0]
+
+	switch name := sel.Sel.Name; name {
+	case "Children", "AppendChildren":
+		for _, arg := range x.Args {
+			appendChildren(n, g.nodes(arg))
+		}
+	case "Text":
+		if len(x.Args) == 1 {
+			n.AppendChild(&html.Node{Type: html.TextNode, Data: g.str(x.Args[0])})
+		}
+	case "Class":
+		var classes []string
+		for _, arg := range x.Args {
+			classes = append(classes, g.str(arg))
+		}
+		if old := attr(n, "class"); len(old) > 0 {
+			classes = append([]string{old}, classes...)
+		}
+		setAttr(n, "class", strings.Join(classes, " "), true)
+	case "Attr":
+		if len(x.Args)%2 != 0 {
+			g.fail(x, "Attr needs key value pairs")
+			return
+		}
+		for i := 0; i < len(x.Args); i += 2 {
+			val, set := g.value(x.Args[i+1])
+			setAttr(n, g.str(x.Args[i]), val, set)
+		}
+	default:
+		if len(x.Args) != 1 {
+			g.fail(x, "can't convert the method %s", name)
+			return
+		}
+		val, set := g.value(x.Args[0])
+		setAttr(n, strings.ToLower(name), val, set)
+	}
+	return
+}
+
+func appendChildren(n *html.Node, children []*html.Node) {
+	for _, c := range children {
+		n.AppendChild(c)
+	}
+}
+
+// str returns the value of the string literal x.
+func (g *goHTML) str(x ast.Expr) string {
+	lit, ok := x.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		g.fail(x, "only string literals can be converted")
+		return ""
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		g.fail(x, "%v", err)
+	}
+	return s
+}
+
+// value returns the attribute value of the literal x, and whether the
+// attribute is set, which false isn't.
+func (g *goHTML) value(x ast.Expr) (val string, set bool) {
+	switch x := x.(type) {
+	case *ast.Ident:
+		switch x.Name {
+		case "true":
+			return "", true
+		case "false":
+			return "", false
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := x.X.(*ast.BasicLit); ok && x.Op == token.SUB && lit.Kind == token.INT {
+			return "-" + lit.Value, true
+		}
+	case *ast.BasicLit:
+		if x.Kind == token.INT {
+			return x.Value, true
+		}
+		return g.str(x), true
+	}
+	g.fail(x, "only literal attribute values can be converted")
+	return
+}
+
+// funcName is the name of the function fun, without the package.
+func funcName(fun ast.Expr) string {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	}
+	return fmt.Sprintf("%T", fun)
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// setAttr sets or, if set is false, removes the attribute key of n.
+func setAttr(n *html.Node, key string, val string, set bool) {
+	for i, a := range n.Attr {
+		if a.Key != key {
+			continue
+		}
+		if set {
+			n.Attr[i].Val = val
+		} else {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+		}
+		return
+	}
+	if set {
+		n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+	}
+}
+
+// renderIndented writes n with its children indented by two spaces, and the
+// text of elements that only have text on their line.
+func renderIndented(buf *bytes.Buffer, n *html.Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch n.Type {
+	case html.TextNode:
+		buf.WriteString(indent + html.EscapeString(n.Data) + "\n")
+		return
+	case html.RawNode:
+		buf.WriteString(indent + n.Data + "\n")
+		return
+	}
+
+	buf.WriteString(indent + "<" + n.Data)
+	for _, a := range n.Attr {
+		buf.WriteString(" " + a.Key)
+		if len(a.Val) > 0 {
+			buf.WriteString(`="` + html.EscapeString(a.Val) + `"`)
+		}
+	}
+	buf.WriteString(">")
+	if strings.Contains(voidElements, "|"+n.Data+"|") {
+		buf.WriteString("\n")
+		return
+	}
+	if c := n.FirstChild; c == nil || c == n.LastChild && c.Type == html.TextNode {
+		if c != nil {
+			buf.WriteString(html.EscapeString(c.Data))
+		}
+		buf.WriteString("</" + n.Data + ">\n")
+		return
+	}
+	buf.WriteString("\n")
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderIndented(buf, c, depth+1)
+	}
+	buf.WriteString(indent + "</" + n.Data + ">\n")
+}
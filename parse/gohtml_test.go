@@ -0,0 +1,80 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+var goToHTMLCases = []struct {
+	name     string
+	code     string
+	expected string
+	err      string
+}{
+	{
+		name: "generated code",
+		code: `Body(
+	Nav(
+		Input("q").Readonly(true).
+			Disabled(false).
+			TabIndex(-1),
+		H1("Hello <you>"),
+		A(
+			Text("Home"),
+		).Href("/").Attr("x-on:click", "open = true"),
+	).Class("navbar").Class("dark"),
+)`,
+		expected: `<body>
+  <nav class="navbar dark">
+    <input name="q" readonly tabindex="-1">
+    <h1>Hello &lt;you&gt;</h1>
+    <a href="/" x-on:click="open = true">Home</a>
+  </nav>
+</body>
+`,
+	},
+	{
+		name: "package prefix, components and tags",
+		code: `h.Components(h.Img("/a.png"), h.Tag("my-card").Children(h.RawHTML("<b>x</b>")), h.Br())`,
+		expected: `<img src="/a.png">
+<my-card>
+  <b>x</b>
+</my-card>
+<br>
+`,
+	},
+	{
+		name: "variable",
+		code: `Div(H1(title))`,
+		err:  "1:8: only string literals can be converted",
+	},
+	{
+		name: "unknown function",
+		code: `Div(UserCard("Alice"))`,
+		err:  "1:5: UserCard isn't an htmlgo element function",
+	},
+}
+
+func TestGoToHTML(t *testing.T) {
+	for _, c := range goToHTMLCases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := parse.GoToHTML(c.code)
+			if len(c.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error %q, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := testingutils.PrettyJsonDiff(c.expected, r)
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// gomponentsBackend generates code for github.com/maragudk/gomponents, with
+// elements and attributes from its html package prefixed by pkg and the core
+// package imported as g.
+type gomponentsBackend struct{}
+
+const gomponentsElements = "|A|Address|Area|Article|Aside|Audio|Base|BlockQuote|Body|Br|Button|Canvas|Cite|" +
+	"Code|Col|ColGroup|DataEl|DataList|Details|Dialog|Div|Dl|Embed|FormEl|FieldSet|Figure|Footer|Head|" +
+	"Header|HGroup|Hr|HTML|IFrame|Img|Input|Label|Legend|Li|Link|Main|Menu|Meta|Meter|Nav|NoScript|" +
+	"Object|Ol|OptGroup|Option|P|Param|Picture|Pre|Progress|Script|Section|Select|Source|Span|StyleEl|" +
+	"Summary|SVG|Table|TBody|Td|Textarea|TFoot|Th|THead|Tr|Ul|Wbr|Abbr|B|Caption|Dd|Del|Dfn|Dt|Em|" +
+	"FigCaption|H1|H2|H3|H4|H5|H6|I|Ins|Kbd|Mark|Q|S|Samp|Small|Strong|Sub|Sup|Time|TitleEl|U|Var|Video|"
+
+const gomponentsBoolAttrs = "|Async|AutoFocus|AutoPlay|Controls|Defer|Disabled|Loop|Multiple|Muted|" +
+	"PlaysInline|ReadOnly|Required|Selected|"
+
+const gomponentsAttrs = "|Accept|Action|Alt|As|AutoComplete|Charset|Class|Cols|Content|For|FormAttr|" +
+	"Height|Href|ID|Lang|Loading|Max|MaxLength|Method|Min|MinLength|Name|Pattern|Placeholder|Poster|" +
+	"Preload|Rel|Role|Rows|Src|SrcSet|StyleAttr|TabIndex|Target|TitleAttr|Type|Value|Width|EncType|"
+
+// gomponents names elements and attributes that clash with each other
+// differently from htmlgo.
+var gomponentsRenames = map[string]string{
+	"data":  "DataEl",
+	"form":  "FormEl",
+	"style": "StyleEl",
+	"title": "TitleEl",
+}
+
+var gomponentsAttrRenames = map[string]string{
+	"form":  "FormAttr",
+	"style": "StyleAttr",
+	"title": "TitleAttr",
+}
+
+func gomponentsName(name string, names string, renames map[string]string) string {
+	if r, ok := renames[strings.ToLower(name)]; ok {
+		return r
+	}
+	for _, n := range strings.Split(strings.Trim(names, "|"), "|") {
+		if strings.EqualFold(n, name) {
+			return n
+		}
+	}
+	return ""
+}
+
+func (gomponentsBackend) componentType(pkg string) string {
+	return "g.Node"
+}
+
+func (gomponentsBackend) imports(pkg string) string {
+	return fmt.Sprintf("import (\n\tg \"github.com/maragudk/gomponents\"\n\t%s \"github.com/maragudk/gomponents/html\"\n)\n",
+		importName(pkg))
+}
+
+func (gomponentsBackend) declares(name string) bool {
+	return strings.Contains(gomponentsElements+gomponentsBoolAttrs+gomponentsAttrs+"Aria|DataAttr|", "|"+name+"|")
+}
+
+func (gb gomponentsBackend) marshal(b *astBuilder, fc *funcCall, methodNames []string, pkg string, childrenMode bool) ast.Expr {
+	return gb.nodes(b, fc, pkg)[0]
+}
+
+// nodes writes the nodes of fc, each followed by ",\n", to b. A condition
+// with an else branch is two nodes.
+func (gb gomponentsBackend) nodes(b *astBuilder, fc *funcCall, pkg string) (r []ast.Expr) {
+	// the source comment goes at the end of the first line of the call
+	comment := fc.srcComment()
+	lineBreak := func() {
+		if len(comment) > 0 {
+			b.comment(comment)
+			comment = ""
+		}
+		b.write("\n")
+	}
+
+	switch {
+	case len(fc.FuncName) > 0:
+		r = append(r, fc.funcCallCode(b))
+	case fc.isText():
+		call := b.call(b.name("g.Text"))
+		call.Args = append(call.Args, fc.textCode(b))
+		r = append(r, b.end(call))
+	case len(fc.Expr) > 0:
+		r = append(r, b.expr(fc.Expr))
+	case len(fc.Cond) > 0 && fc.Lazy:
+		r = append(r, gb.lazyIf(b, fc, pkg))
+	case len(fc.Cond) > 0:
+		r = append(r, gb.ifNode(b, fc.Cond, fc.Children, pkg))
+		if len(fc.Else) > 0 {
+			b.write(",\n")
+			r = append(r, gb.ifNode(b, fmt.Sprintf("!(%s)", fc.Cond), fc.Else, pkg))
+		}
+	case len(fc.RangeExpr) > 0:
+		r = append(r, gb.marshalRange(b, fc, pkg))
+	case fc.Implicit:
+		r = append(r, gb.group(b, fc.Children, pkg))
+	default:
+		r = append(r, gb.marshalElement(b, fc, pkg, lineBreak))
+	}
+
+	b.write(",")
+	lineBreak()
+	return
+}
+
+func (gb gomponentsBackend) children(b *astBuilder, children []*funcCall, pkg string) (r []ast.Expr) {
+	for _, c := range children {
+		r = append(r, gb.nodes(b, c, pkg)...)
+	}
+	return
+}
+
+// group returns g.Group([]g.Node{children}).
+func (gb gomponentsBackend) group(b *astBuilder, children []*funcCall, pkg string) ast.Expr {
+	call := b.call(b.name("g.Group"))
+	lit := &ast.CompositeLit{Type: &ast.ArrayType{Lbrack: b.write("["), Elt: b.name("g.Node")}}
+	b.write("]")
+	lit.Lbrace = b.write("{")
+	b.write("\n")
+	lit.Elts = gb.children(b, children, pkg)
+	lit.Rbrace = b.write("}")
+	call.Args = append(call.Args, lit)
+	return b.end(call)
+}
+
+func (gb gomponentsBackend) ifNode(b *astBuilder, cond string, children []*funcCall, pkg string) ast.Expr {
+	call := b.call(b.name("g.If"))
+	call.Args = append(call.Args, b.expr(cond))
+	b.write(", ")
+	call.Args = append(call.Args, gb.group(b, children, pkg))
+	return b.end(call)
+}
+
+// lazyIf returns the group of the children of fc built only if its condition
+// holds, or else of its else branch.
+func (gb gomponentsBackend) lazyIf(b *astBuilder, fc *funcCall, pkg string) ast.Expr {
+	children := func(fcs []*funcCall) func() []ast.Expr {
+		return func() []ast.Expr {
+			return gb.children(b, fcs, pkg)
+		}
+	}
+	var els func() []ast.Expr
+	if len(fc.Else) > 0 {
+		els = children(fc.Else)
+	}
+	call := b.call(b.name("g.Group"))
+	call.Args = append(call.Args, b.ifLit("[]g.Node", fc.Cond, children(fc.Children), els))
+	return b.end(call)
+}
+
+func (gb gomponentsBackend) marshalRange(b *astBuilder, fc *funcCall, pkg string) ast.Expr {
+	children := func(fcs []*funcCall) func() []ast.Expr {
+		return func() []ast.Expr {
+			return gb.children(b, fcs, pkg)
+		}
+	}
+	var els func() []ast.Expr
+	if len(fc.Else) > 0 {
+		els = children(fc.Else)
+	}
+	call := b.call(b.name("g.Group"))
+	call.Args = append(call.Args, b.rangeLit("[]g.Node", fc, children(fc.Children), els))
+	return b.end(call)
+}
+
+func (gb gomponentsBackend) marshalElement(b *astBuilder, fc *funcCall, pkg string, lineBreak func()) ast.Expr {
+	// elements gomponents has no function for are built with g.El, whose
+	// first argument is the tag name
+	name := gomponentsName(strcase.ToCamel(fc.Name), gomponentsElements, gomponentsRenames)
+	var call *ast.CallExpr
+	if len(fc.Func) > 0 {
+		call = b.call(b.name(fc.Func))
+	} else if len(name) > 0 {
+		call = b.call(b.name(pkgDot(pkg) + name))
+	} else {
+		call = b.call(b.name("g.El"))
+		call.Args = append(call.Args, b.str(fc.Tag))
+	}
+	writeArg := func(arg func() ast.Expr) {
+		if len(call.Args) > 0 {
+			b.write(", ")
+		}
+		call.Args = append(call.Args, arg())
+	}
+
+	for _, att := range fc.orderedAttrs(b.attrOrder, gb.typedAttr) {
+		writeArg(func() ast.Expr {
+			return gb.attr(b, fc, att.Key, att.Val, pkg)
+		})
+	}
+
+	if len(fc.Children) == 1 && fc.Children[0].isText() {
+		writeArg(func() ast.Expr {
+			text := b.call(b.name("g.Text"))
+			text.Args = append(text.Args, fc.Children[0].textCode(b))
+			return b.end(text)
+		})
+	} else if len(fc.Children) > 0 {
+		if len(call.Args) > 0 {
+			b.write(",")
+		}
+		lineBreak()
+		call.Args = append(call.Args, gb.children(b, fc.Children, pkg)...)
+	}
+	return b.end(call)
+}
+
+// typedAttr reports whether gomponents has a function for the attribute.
+func (gb gomponentsBackend) typedAttr(key string) bool {
+	camel := strcase.ToCamel(strings.ToLower(key))
+	return !strings.ContainsAny(key, ":@.") && (len(gomponentsName(camel, gomponentsBoolAttrs, nil)) > 0 ||
+		len(gomponentsName(camel, gomponentsAttrs, gomponentsAttrRenames)) > 0)
+}
+
+// attr returns the gomponents attribute key with the value val.
+func (gb gomponentsBackend) attr(b *astBuilder, fc *funcCall, key string, val string, pkg string) ast.Expr {
+	value := func() ast.Expr {
+		if expr, ok := fc.AttrExprs[key]; ok {
+			return b.expr(expr)
+		}
+		return b.lit(normalizeGoString(val).(string))
+	}
+	call := func(name string, args ...func() ast.Expr) ast.Expr {
+		c := b.call(b.name(name))
+		for i, arg := range args {
+			if i > 0 {
+				b.write(", ")
+			}
+			c.Args = append(c.Args, arg())
+		}
+		return b.end(c)
+	}
+	str := func(s string) func() ast.Expr {
+		return func() ast.Expr { return b.str(s) }
+	}
+
+	key = expandAlpineKey(key)
+	lower := strings.ToLower(key)
+	switch {
+	case strings.ContainsAny(key, ":@."):
+		return call("g.Attr", str(key), value)
+	case strings.HasPrefix(lower, "data-"):
+		return call(pkgDot(pkg)+"DataAttr", str(strings.TrimPrefix(lower, "data-")), value)
+	case strings.HasPrefix(lower, "aria-"):
+		return call(pkgDot(pkg)+"Aria", str(strings.TrimPrefix(lower, "aria-")), value)
+	}
+
+	camel := strcase.ToCamel(lower)
+	if n := gomponentsName(camel, gomponentsBoolAttrs, nil); len(n) > 0 {
+		return call(pkgDot(pkg) + n)
+	}
+	if n := gomponentsName(camel, gomponentsAttrs, gomponentsAttrRenames); len(n) > 0 {
+		return call(pkgDot(pkg)+n, value)
+	}
+	if len(val) == 0 {
+		return call("g.Attr", str(key))
+	}
+	return call("g.Attr", str(key), value)
+}
@@ -0,0 +1,96 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestGomponentsTarget(t *testing.T) {
+	var cases = []struct {
+		name         string
+		pkg          string
+		placeholders bool
+		html         string
+		gocode       string
+	}{
+		{
+			name: "elements and attributes",
+			pkg:  "html",
+			html: `
+<nav class="navbar" id="main">
+  <a href="#" data-toggle="collapse" aria-label="Home">Home <span class="sr-only">(current)</span></a>
+  <input disabled readonly tabindex="-1" :class="{active: on}" x-cloak>
+  <form style="color: red"><my-card title="Card">Hi</my-card></form>
+</nav>
+`,
+			gocode: `package hello
+
+var n = html.Body(
+	html.Nav(html.Class("navbar"), html.ID("main"),
+		html.A(html.Href("#"), html.DataAttr("toggle", "collapse"), html.Aria("label", "Home"),
+			g.Text("Home"),
+			html.Span(html.Class("sr-only"), g.Text("(current)")),
+		),
+		html.Input(html.Disabled(), html.ReadOnly(), html.TabIndex("-1"), g.Attr("x-bind:class", "{active: on}"), g.Attr("x-cloak")),
+		html.FormEl(html.StyleAttr("color: red"),
+			g.El("my-card", html.TitleAttr("Card"), g.Text("Hi")),
+		),
+	),
+)
+`,
+		},
+		{
+			name:         "placeholders",
+			placeholders: true,
+			html: `
+<ul>
+  {% for item in items %}<li>{{ item }}</li>{% endfor %}
+  {% if empty %}<li>None</li>{% else %}<li>{{ count }}</li>{% endif %}
+</ul>
+`,
+			gocode: `package hello
+
+func Component(items []string, empty bool, count string) g.Node {
+	return Body(
+		Ul(
+			g.Group(func() (r []g.Node) {
+				for _, item := range items {
+					r = append(r,
+						Li(g.Text(item)),
+					)
+				}
+				return
+			}()),
+			g.If(empty, g.Group([]g.Node{
+				Li(g.Text("None")),
+			})),
+			g.If(!(empty), g.Group([]g.Node{
+				Li(g.Text(count)),
+			})),
+		),
+	)
+}
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := &parse.Config{Target: parse.TargetGomponents}
+			var gocode string
+			if c.placeholders {
+				gocode = parse.GenerateHTMLGoFromPlaceholders(c.pkg, false, cfg, strings.NewReader(c.html))
+			} else {
+				gocode = parse.GenerateHTMLGoWithConfig(c.pkg, false, cfg, strings.NewReader(c.html))
+			}
+			diff := testingutils.PrettyJsonDiff(c.gocode, gocode)
+
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
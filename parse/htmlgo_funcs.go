@@ -0,0 +1,25 @@
+// Code generated by gen_htmlgo_funcs.go. DO NOT EDIT.
+
+package parse
+
+// htmlgoFuncs are the element functions of htmlgo.
+const htmlgoFuncs = "|" +
+	"A|Abbr|Address|Area|Article|Aside|Audio|B|Base|Bdi|Bdo|Blockquote|Body|Br|Button|Canvas|" +
+	"Caption|Cite|Code|Col|Colgroup|Data|Datalist|Dd|Del|Details|Dfn|Dialog|Div|Dl|Dt|Em|Embed|" +
+	"Fieldset|Figcaption|Figure|Footer|Form|H1|H2|H3|H4|H5|H6|HTML|Head|Header|Hgroup|Hr|I|" +
+	"Iframe|Img|Input|Ins|Kbd|Label|Legend|Li|Link|Main|Map|Mark|Menu|Meta|Meter|Nav|Noscript|" +
+	"Object|Ol|Optgroup|Option|Output|P|Param|Picture|Pre|Progress|Q|Rp|Rt|Ruby|S|Samp|Script|" +
+	"Section|Select|Slot|Small|Source|Span|Strong|Style|Sub|Summary|Sup|Table|Tbody|Td|Template|" +
+	"Textarea|Tfoot|Th|Thead|Time|Title|Tr|Track|U|Ul|Var|Video|Wbr|"
+
+// htmlgoNames are all the names htmlgo exports.
+const htmlgoNames = "|" +
+	"A|Abbr|Address|Area|Article|Aside|Audio|B|Base|Bdi|Bdo|Blockquote|Body|Br|Button|Canvas|" +
+	"Caption|Cite|Code|Col|Colgroup|ComponentFunc|Components|Data|Datalist|Dd|Del|Details|Dfn|" +
+	"Dialog|Div|Dl|Dt|Em|Embed|Fieldset|Figcaption|Figure|Footer|Form|Fprint|H1|H2|H3|H4|H5|H6|" +
+	"HTML|HTMLComponent|HTMLComponents|HTMLTagBuilder|Head|Header|Hgroup|Hr|I|If|IfBuilder|" +
+	"IfFuncBuilder|Iff|Iframe|Img|Input|Ins|JSONString|Kbd|Label|Legend|Li|Link|Main|Map|Mark|" +
+	"Menu|Meta|Meter|MustString|MutableAttrHTMLComponent|Nav|Noscript|Object|Ol|Optgroup|Option|" +
+	"Output|P|Param|Picture|Pre|Progress|Q|RawHTML|Rp|Rt|Ruby|S|Samp|Script|Section|Select|Slot|" +
+	"Small|Source|Span|Strong|Style|Sub|Summary|Sup|Table|Tag|Tbody|Td|Template|Text|Textarea|" +
+	"Textf|Tfoot|Th|Thead|Time|Title|Tr|Track|U|Ul|Var|Video|Wbr|"
@@ -0,0 +1,265 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io"
+	"strings"
+)
+
+// Markers of a region of a Go file replaced by InsertRegion.
+const (
+	regionBegin = "// html2go:begin "
+	regionEnd   = "// html2go:end"
+)
+
+// InsertFunc converts the HTML and puts the component into the function or
+// method funcName of the Go file src, replacing the value of its last return
+// statement, or its whole body if it has none. A single element isn't
+// wrapped in a body. The functions extracted from
+// the HTML replace those of the same name in src or are appended to it. All
+// other code and comments of src are kept.
+func (c *Converter) InsertFunc(src []byte, funcName string, htmlCode io.Reader) (r []byte, err error) {
+	fset, f, err := parseGoFile(src)
+	if err != nil {
+		return
+	}
+	var fd *ast.FuncDecl
+	for _, d := range f.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && d.Name.Name == funcName && d.Body != nil {
+			fd = d
+		}
+	}
+	if fd == nil {
+		err = fmt.Errorf("no function %s", funcName)
+		return
+	}
+
+	// only the expression of the component is inserted
+	c2 := *c
+	c2.opts.Shape = ShapeVar
+	gfset, gf, err := c2.ConvertAST(htmlCode)
+	if err != nil {
+		return
+	}
+	expr, err := printNode(gfset, gf, componentExpr(gf))
+	if err != nil {
+		return
+	}
+
+	var edits []edit
+	if ret := lastReturn(fd.Body); ret != nil && len(ret.Results) == 1 {
+		edits = append(edits, newEdit(fset, ret.Results[0].Pos(), ret.Results[0].End(), expr))
+	} else {
+		edits = append(edits, newEdit(fset, fd.Body.Lbrace, fd.Body.End(), "{\nreturn "+expr+"\n}"))
+	}
+	funcEdits, err := componentFuncEdits(fset, f, gfset, gf)
+	if err != nil {
+		return
+	}
+	return applyEdits(src, append(edits, funcEdits...))
+}
+
+// InsertRegion converts the HTML and puts it between the comments
+//
+//	// html2go:begin name
+//	// html2go:end
+//
+// of the Go file src. Inside the arguments of a call the region gets the
+// children of the body, between declarations the var declaration of name. The
+// functions extracted from the HTML replace those of the same name in src or
+// are appended to it. All other code and comments of src are kept.
+func (c *Converter) InsertRegion(src []byte, name string, htmlCode io.Reader) (r []byte, err error) {
+	fset, f, err := parseGoFile(src)
+	if err != nil {
+		return
+	}
+	var begin, end *ast.Comment
+	for _, cg := range f.Comments {
+		for _, cm := range cg.List {
+			if begin == nil && strings.TrimSpace(cm.Text) == regionBegin+name {
+				begin = cm
+			} else if begin != nil && end == nil && strings.TrimSpace(cm.Text) == regionEnd {
+				end = cm
+			}
+		}
+	}
+	if begin == nil || end == nil {
+		err = fmt.Errorf("no %s%s ... %s region", regionBegin, name, regionEnd)
+		return
+	}
+
+	// the enclosing call, if any, is the innermost one
+	var call *ast.CallExpr
+	inDecl := false
+	ast.Inspect(f, func(n ast.Node) bool {
+		if n == nil || n.Pos() > begin.Pos() || n.End() < end.End() {
+			return n == f
+		}
+		if _, ok := n.(ast.Decl); ok {
+			inDecl = true
+		}
+		if ce, ok := n.(*ast.CallExpr); ok && ce.Lparen < begin.Pos() && ce.Rparen > end.End() {
+			call = ce
+		}
+		return true
+	})
+
+	c2 := *c
+	c2.opts.Shape = ShapeVar
+	if call == nil {
+		if inDecl {
+			err = fmt.Errorf("the %s region must be inside the arguments of a call or between declarations", name)
+			return
+		}
+		c2.opts.Name = name
+	}
+	gfset, gf, err := c2.ConvertAST(htmlCode)
+	if err != nil {
+		return
+	}
+
+	code := bytes.NewBuffer(nil)
+	if call != nil {
+		body := gf.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.CallExpr)
+		for _, arg := range body.Args {
+			s, perr := printNode(gfset, gf, arg)
+			if perr != nil {
+				return nil, perr
+			}
+			code.WriteString(s + ",\n")
+		}
+		gf.Decls = gf.Decls[1:]
+	} else {
+		s, perr := printNode(gfset, gf, gf.Decls[0])
+		if perr != nil {
+			return nil, perr
+		}
+		code.WriteString(s + "\n")
+		gf.Decls = gf.Decls[1:]
+	}
+
+	edits := []edit{newEdit(fset, begin.End(), lineStart(fset, src, end.Pos()), "\n"+code.String())}
+	funcEdits, err := componentFuncEdits(fset, f, gfset, gf)
+	if err != nil {
+		return
+	}
+	return applyEdits(src, append(edits, funcEdits...))
+}
+
+// componentExpr is the component of the file ConvertAST generates in the
+// var shape, without the body around a single element.
+func componentExpr(f *ast.File) ast.Expr {
+	value := f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0]
+	if body, ok := value.(*ast.CallExpr); ok && len(body.Args) == 1 && isBodyFunc(body.Fun) {
+		return body.Args[0]
+	}
+	return value
+}
+
+func isBodyFunc(fun ast.Expr) bool {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return fun.Name == "Body"
+	case *ast.SelectorExpr:
+		_, ok := fun.X.(*ast.Ident)
+		return ok && fun.Sel.Name == "Body"
+	}
+	return false
+}
+
+func parseGoFile(src []byte) (fset *token.FileSet, f *ast.File, err error) {
+	fset = token.NewFileSet()
+	f, err = parser.ParseFile(fset, "", src, parser.ParseComments)
+	return
+}
+
+func lastReturn(body *ast.BlockStmt) *ast.ReturnStmt {
+	for i := len(body.List) - 1; i >= 0; i-- {
+		if ret, ok := body.List[i].(*ast.ReturnStmt); ok {
+			return ret
+		}
+	}
+	return nil
+}
+
+// lineStart is the offset of the start of the line of pos.
+func lineStart(fset *token.FileSet, src []byte, pos token.Pos) token.Pos {
+	offset := fset.Position(pos).Offset
+	return pos - token.Pos(offset-(bytes.LastIndexByte(src[:offset], '\n')+1))
+}
+
+func printNode(fset *token.FileSet, f *ast.File, n ast.Node) (r string, err error) {
+	buf := bytes.NewBuffer(nil)
+	err = printerConfig.Fprint(buf, fset, &printer.CommentedNode{Node: n, Comments: f.Comments})
+	r = buf.String()
+	return
+}
+
+// componentFuncEdits replaces the functions of src extracted from the HTML
+// again, and appends the new ones.
+func componentFuncEdits(fset *token.FileSet, f *ast.File, gfset *token.FileSet, gf *ast.File) (r []edit, err error) {
+	existing := map[string]*ast.FuncDecl{}
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil {
+			existing[fd.Name.Name] = fd
+		}
+	}
+	for _, d := range gf.Decls {
+		fd, ok := d.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		code, perr := printNode(gfset, gf, fd)
+		if perr != nil {
+			return nil, perr
+		}
+		if old, ok := existing[fd.Name.Name]; ok {
+			r = append(r, newEdit(fset, old.Pos(), old.End(), code))
+			continue
+		}
+		end := fset.File(f.Pos()).Size()
+		r = append(r, edit{start: end, end: end, code: "\n\n" + code + "\n"})
+	}
+	return
+}
+
+// edit replaces the bytes from start to end of the source by code.
+type edit struct {
+	start int
+	end   int
+	code  string
+}
+
+func newEdit(fset *token.FileSet, start token.Pos, end token.Pos, code string) edit {
+	return edit{start: fset.Position(start).Offset, end: fset.Position(end).Offset, code: code}
+}
+
+// applyEdits applies the edits, which don't overlap, to src and formats the
+// result.
+func applyEdits(src []byte, edits []edit) (r []byte, err error) {
+	buf := bytes.NewBuffer(nil)
+	last := 0
+	for len(edits) > 0 {
+		// the next edit in the source
+		next := 0
+		for i, e := range edits {
+			if e.start < edits[next].start {
+				next = i
+			}
+		}
+		e := edits[next]
+		edits = append(edits[:next], edits[next+1:]...)
+
+		buf.Write(src[last:e.start])
+		buf.WriteString(e.code)
+		last = e.end
+	}
+	buf.Write(src[last:])
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,125 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+const insertPage = `package page
+
+import . "github.com/theplant/htmlgo"
+
+// RenderHeader renders the page header.
+func RenderHeader(title string) HTMLComponent {
+	if title == "" {
+		title = "Home" // default
+	}
+	return Div(Text("old"))
+}
+
+func Layout(body HTMLComponent) HTMLComponent {
+	return Div(
+		// html2go:begin nav
+		Text("old nav"),
+		// html2go:end
+		body,
+	)
+}
+
+// html2go:begin footer
+var footer = Text("old")
+
+// html2go:end
+
+func More() HTMLComponent { return nil }
+`
+
+func TestInsertFunc(t *testing.T) {
+	// the shape of the options doesn't matter, only the expression is inserted
+	for _, shape := range []string{"", parse.ShapeFunc, parse.ShapeMethod} {
+		t.Run("shape "+shape, func(t *testing.T) {
+			testInsertFunc(t, shape)
+		})
+	}
+}
+
+func testInsertFunc(t *testing.T, shape string) {
+	c := parse.NewConverter(parse.Options{Config: parse.Config{Shape: shape}})
+	r, err := c.InsertFunc([]byte(insertPage), "RenderHeader",
+		strings.NewReader(`<header><h1>Hi</h1><a data-html2go-func="More" href="/more">More</a></header>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Replace(insertPage, `	return Div(Text("old"))`, `	return Header(
+		H1("Hi"),
+		More(),
+	)`, 1)
+	expected = strings.Replace(expected, `func More() HTMLComponent { return nil }`, `func More() HTMLComponent {
+	return A(
+		Text("More"),
+	).Href("/more")
+}`, 1)
+	diff := testingutils.PrettyJsonDiff(expected, string(r))
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+}
+
+func TestInsertRegion(t *testing.T) {
+	cases := []struct {
+		name     string
+		region   string
+		html     string
+		old      string
+		expected string
+	}{
+		{
+			name:   "call arguments",
+			region: "nav",
+			html:   `<a href="/">Home</a><a href="/about">About</a>`,
+			old:    `		Text("old nav"),`,
+			expected: `		A(
+			Text("Home"),
+		).Href("/"),
+		A(
+			Text("About"),
+		).Href("/about"),`,
+		},
+		{
+			name:   "declarations",
+			region: "footer",
+			html:   `<footer>(c)</footer>`,
+			old:    `var footer = Text("old")`,
+			expected: `var footer = Body(
+	Footer(
+		Text("(c)"),
+	),
+)`,
+		},
+	}
+
+	for _, cs := range cases {
+		for _, shape := range []string{"", parse.ShapeFunc, parse.ShapeMethod} {
+			t.Run(cs.name+" shape "+shape, func(t *testing.T) {
+				c := parse.NewConverter(parse.Options{Config: parse.Config{Shape: shape}})
+				r, err := c.InsertRegion([]byte(insertPage), cs.region, strings.NewReader(cs.html))
+				if err != nil {
+					t.Fatal(err)
+				}
+				diff := testingutils.PrettyJsonDiff(strings.Replace(insertPage, cs.old, cs.expected, 1), string(r))
+				if len(diff) > 0 {
+					t.Error(diff)
+				}
+			})
+		}
+	}
+
+	_, err := parse.NewConverter(parse.Options{}).InsertRegion([]byte(insertPage), "missing", strings.NewReader(`<p></p>`))
+	if err == nil {
+		t.Error("expected an error for a missing region")
+	}
+}
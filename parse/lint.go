@@ -0,0 +1,159 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Diagnostic is markup the HTML parser repairs, so that the generated code
+// differs from the input.
+type Diagnostic struct {
+	// Line and Column of the input the repair happens at, starting at 1.
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s", d.Line, d.Column, d.Message)
+}
+
+// optionalEndTags are the elements whose end tag may be omitted.
+const optionalEndTags = "|html|head|body|p|li|dt|dd|option|optgroup|tr|td|th|tbody|thead|tfoot|colgroup|caption|rp|rt|"
+
+// closesP are the start tags that close an open <p>.
+const closesP = "|address|article|aside|blockquote|details|dialog|div|dl|fieldset|figcaption|figure|footer|form|" +
+	"h1|h2|h3|h4|h5|h6|header|hgroup|hr|main|menu|nav|ol|p|pre|section|table|ul|"
+
+// implicitlyClosed are the open elements a start tag closes.
+var implicitlyClosed = map[string]string{
+	"li":       "|li|",
+	"dt":       "|dt|dd|",
+	"dd":       "|dt|dd|",
+	"option":   "|option|",
+	"optgroup": "|option|optgroup|",
+	"tr":       "|tr|td|th|",
+	"td":       "|td|th|",
+	"th":       "|td|th|",
+	"tbody":    "|tbody|thead|tfoot|tr|td|th|",
+	"thead":    "|tbody|thead|tfoot|tr|td|th|",
+	"tfoot":    "|tbody|thead|tfoot|tr|td|th|",
+}
+
+type openElement struct {
+	tag    string
+	line   int
+	column int
+	// reported elements get no other diagnostic when closed
+	reported bool
+}
+
+// Lint tokenizes the HTML of r and returns a diagnostic for every error the
+// parser recovers from: elements that are never closed or closed by the end
+// tag of an outer element, end tags without an open element, elements
+// nested where they can't be, and self-closing tags of non-void elements.
+func Lint(r io.Reader) (ds []Diagnostic, err error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+
+	var open []*openElement
+	pos := position{line: 1, column: 1}
+	report := func(line, column int, format string, a ...interface{}) {
+		ds = append(ds, Diagnostic{Line: line, Column: column, Message: fmt.Sprintf(format, a...)})
+	}
+	isOpen := func(tag string) int {
+		for i := len(open) - 1; i >= 0; i-- {
+			if open[i].tag == tag {
+				return i
+			}
+		}
+		return -1
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tokLine, tokColumn := pos.line, pos.column
+		pos.advance(z.Raw())
+		tok := z.Token()
+
+		reported := false
+		if tt == html.SelfClosingTagToken {
+			// only void and foreign elements, like the paths of an <svg>,
+			// can be self-closing
+			if tok.Data == "svg" || tok.Data == "math" || isOpen("svg") >= 0 || isOpen("math") >= 0 {
+				continue
+			}
+			if !strings.Contains(voidElements, "|"+tok.Data+"|") {
+				report(tokLine, tokColumn, "<%s/> isn't closed by the /, the content after it is nested in it", tok.Data)
+				reported = true
+			}
+			tt = html.StartTagToken
+		}
+
+		switch tt {
+		case html.StartTagToken:
+			if closes, ok := implicitlyClosed[tok.Data]; ok {
+				for len(open) > 0 && strings.Contains(closes, "|"+open[len(open)-1].tag+"|") {
+					open = open[:len(open)-1]
+				}
+			}
+			if strings.Contains(closesP, "|"+tok.Data+"|") {
+				if i := isOpen("p"); i >= 0 && i == len(open)-1 {
+					open = open[:i]
+				}
+			}
+			if i := isOpen(tok.Data); i >= 0 && tok.Data == "a" {
+				report(tokLine, tokColumn, "<a> inside the <a> from %d:%d closes it", open[i].line, open[i].column)
+				open = append(open[:i], open[i+1:]...)
+			}
+			if i := isOpen(tok.Data); i >= 0 && tok.Data == "form" {
+				report(tokLine, tokColumn, "<form> inside the <form> from %d:%d is dropped", open[i].line, open[i].column)
+				continue
+			}
+			if strings.Contains(voidElements, "|"+tok.Data+"|") {
+				continue
+			}
+			open = append(open, &openElement{tag: tok.Data, line: tokLine, column: tokColumn, reported: reported})
+
+		case html.EndTagToken:
+			i := isOpen(tok.Data)
+			if i < 0 {
+				if tok.Data == "p" {
+					report(tokLine, tokColumn, "</p> without an open <p>, an empty <p> is inserted")
+				} else {
+					report(tokLine, tokColumn, "</%s> without an open <%s> is dropped", tok.Data, tok.Data)
+				}
+				continue
+			}
+			for _, e := range open[i+1:] {
+				if e.reported || strings.Contains(optionalEndTags, "|"+e.tag+"|") {
+					continue
+				}
+				report(tokLine, tokColumn, "</%s> closes the unclosed <%s> from %d:%d", tok.Data, e.tag, e.line, e.column)
+			}
+			open = open[:i]
+		}
+	}
+
+	if err = z.Err(); err == io.EOF {
+		err = nil
+	}
+	for _, e := range open {
+		if e.reported || strings.Contains(optionalEndTags, "|"+e.tag+"|") {
+			continue
+		}
+		report(e.line, e.column, "<%s> is never closed", e.tag)
+	}
+	return
+}
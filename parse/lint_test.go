@@ -0,0 +1,87 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestLint(t *testing.T) {
+	cases := []struct {
+		name     string
+		html     string
+		expected []string
+	}{
+		{
+			name: "valid markup with omitted end tags",
+			html: `<ul><li>One<li>Two</ul>
+<table><tr><td>A<td>B</table>
+<p>First<p>Second
+<img src="a.png"><br/>`,
+		},
+		{
+			name: "self-closing non-void elements",
+			html: `<div><my-icon/><p>x</p></div>
+<span/>text
+<svg><path d="M0"/></svg><hr/>`,
+			expected: []string{
+				"1:6: <my-icon/> isn't closed by the /, the content after it is nested in it",
+				"2:1: <span/> isn't closed by the /, the content after it is nested in it",
+			},
+		},
+		{
+			name: "div inside p",
+			html: `<p>Intro<div>Block</div></p>`,
+			expected: []string{
+				"1:25: </p> without an open <p>, an empty <p> is inserted",
+			},
+		},
+		{
+			name: "misnested and stray end tags",
+			html: `<div>
+  <span>Open</div>
+</span>`,
+			expected: []string{
+				"2:13: </div> closes the unclosed <span> from 2:3",
+				"3:1: </span> without an open <span> is dropped",
+			},
+		},
+		{
+			name: "nested links and forms",
+			html: `<a href="/">x<a href="/y">y</a>
+<form><form></form>`,
+			expected: []string{
+				"1:14: <a> inside the <a> from 1:1 closes it",
+				"2:7: <form> inside the <form> from 2:1 is dropped",
+			},
+		},
+		{
+			name: "unclosed elements",
+			html: `<section>
+	<h1>Title`,
+			expected: []string{
+				"1:1: <section> is never closed",
+				"2:2: <h1> is never closed",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ds, err := parse.Lint(strings.NewReader(c.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var actual []string
+			for _, d := range ds {
+				actual = append(actual, d.String())
+			}
+			diff := testingutils.PrettyJsonDiff(c.expected, actual)
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
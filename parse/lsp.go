@@ -0,0 +1,374 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Titles of the code actions of the LanguageServer.
+const (
+	actionHTMLToGo = "Convert selected HTML to htmlgo"
+	actionGoToHTML = "Convert htmlgo expression to HTML"
+)
+
+// LanguageServer is a Language Server Protocol server offering code actions
+// that convert the selected HTML into Go code with Converter, and the
+// selected htmlgo expression back into HTML with GoToHTML. It keeps the
+// text of the open documents in sync.
+type LanguageServer struct {
+	Converter *Converter
+
+	docs map[string]string
+	out  io.Writer
+}
+
+// JSON-RPC error codes.
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+type rpcMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type textEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type codeAction struct {
+	Title string        `json:"title"`
+	Kind  string        `json:"kind"`
+	Edit  workspaceEdit `json:"edit"`
+}
+
+type workspaceEdit struct {
+	Changes map[string][]textEdit `json:"changes"`
+}
+
+// Serve answers the requests read from in on out until the client exits or
+// in ends.
+func (s *LanguageServer) Serve(in io.Reader, out io.Writer) error {
+	s.docs = map[string]string{}
+	s.out = out
+	r := bufio.NewReader(in)
+	for {
+		body, err := readMessage(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var msg rpcMessage
+		err = json.Unmarshal(body, &msg)
+		if err != nil {
+			err = s.reply(nil, nil, &rpcError{Code: rpcParseError, Message: err.Error()})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, rerr := s.safeHandle(msg.Method, msg.Params)
+		// notifications have no id and get no reply
+		if msg.ID == nil {
+			continue
+		}
+		err = s.reply(msg.ID, result, rerr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// safeHandle is handle answering an internal error instead of panicking, so
+// that a bad request doesn't end the server.
+func (s *LanguageServer) safeHandle(method string, params json.RawMessage) (result interface{}, rerr *rpcError) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, rerr = nil, &rpcError{Code: rpcInternalError, Message: fmt.Sprint(r)}
+		}
+	}()
+	return s.handle(method, params)
+}
+
+// handle returns the result of the request or notification method.
+func (s *LanguageServer) handle(method string, params json.RawMessage) (result interface{}, rerr *rpcError) {
+	invalid := func(err error) (interface{}, *rpcError) {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	switch method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": 1,
+				"codeActionProvider": map[string]interface{}{
+					"codeActionKinds": []string{"refactor.rewrite"},
+				},
+			},
+			"serverInfo": map[string]string{"name": "html2go"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument textDocumentItem `json:"textDocument"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return invalid(err)
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		return nil, nil
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument   textDocumentItem `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return invalid(err)
+		}
+		// the server only asks for full text changes
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument textDocumentItem `json:"textDocument"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return invalid(err)
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/codeAction":
+		var p struct {
+			TextDocument textDocumentItem `json:"textDocument"`
+			Range        lspRange         `json:"range"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return invalid(err)
+		}
+		actions, err := s.codeActions(p.TextDocument.URI, p.Range)
+		if err != nil {
+			return invalid(err)
+		}
+		return actions, nil
+	}
+	if strings.HasPrefix(method, "$/") || method == "initialized" {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + method}
+}
+
+func (s *LanguageServer) reply(id *json.RawMessage, result interface{}, rerr *rpcError) (err error) {
+	msg := rpcMessage{JSONRPC: "2.0", ID: id, Error: rerr}
+	if id == nil {
+		null := json.RawMessage("null")
+		msg.ID = &null
+	}
+	if rerr == nil {
+		msg.Result, err = json.Marshal(result)
+		if err != nil {
+			return
+		}
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return
+}
+
+// readMessage reads the body of the next message of the base protocol.
+func readMessage(r *bufio.Reader) (body []byte, err error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			break
+		}
+		if v := strings.TrimPrefix(line, "Content-Length:"); v != line {
+			length, err = strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %w", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+	body = make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return
+}
+
+// codeActions returns the conversions of the text selected by rg, none if
+// the text converts neither way.
+func (s *LanguageServer) codeActions(uri string, rg lspRange) (r []codeAction, err error) {
+	r = []codeAction{}
+	doc, ok := s.docs[uri]
+	if !ok {
+		return
+	}
+	start, end := docOffset(doc, rg.Start), docOffset(doc, rg.End)
+	if end < start {
+		err = fmt.Errorf("range ends at %d:%d before its start %d:%d",
+			rg.End.Line, rg.End.Character, rg.Start.Line, rg.Start.Character)
+		return
+	}
+	selected := doc[start:end]
+	if len(strings.TrimSpace(selected)) == 0 {
+		return
+	}
+	indent := lineIndent(doc, start)
+
+	if strings.HasPrefix(strings.TrimSpace(selected), "<") {
+		code, funcs, err := s.convertSelection(selected)
+		if err == nil {
+			edits := []textEdit{{Range: rg, NewText: indentLines(code, indent)}}
+			if len(funcs) > 0 {
+				docEnd := docPosition(doc, len(doc))
+				edits = append(edits, textEdit{Range: lspRange{docEnd, docEnd}, NewText: "\n" + funcs})
+			}
+			r = append(r, codeAction{
+				Title: actionHTMLToGo,
+				Kind:  "refactor.rewrite",
+				Edit:  workspaceEdit{Changes: map[string][]textEdit{uri: edits}},
+			})
+		}
+	}
+
+	if htmlCode, err := GoToHTML(selected); err == nil {
+		r = append(r, codeAction{
+			Title: actionGoToHTML,
+			Kind:  "refactor.rewrite",
+			Edit: workspaceEdit{Changes: map[string][]textEdit{uri: {
+				{Range: rg, NewText: indentLines(strings.TrimSuffix(htmlCode, "\n"), indent)},
+			}}},
+		})
+	}
+	return
+}
+
+// convertSelection returns the expression of the HTML, without the body
+// around a single element, and the functions extracted from it.
+func (s *LanguageServer) convertSelection(htmlCode string) (code string, funcs string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	c := *s.Converter
+	c.opts.Shape = ShapeVar
+	fset, f, err := c.ConvertAST(strings.NewReader(htmlCode))
+	if err != nil {
+		return
+	}
+	code, err = printNode(fset, f, componentExpr(f))
+	if err != nil {
+		return
+	}
+	buf := bytes.NewBuffer(nil)
+	for _, d := range f.Decls[1:] {
+		fd, perr := printNode(fset, f, d)
+		if perr != nil {
+			return "", "", perr
+		}
+		buf.WriteString("\n" + fd + "\n")
+	}
+	funcs = buf.String()
+	return
+}
+
+// docOffset returns the byte offset of pos in text, whose characters are
+// counted in UTF-16 code units.
+func docOffset(text string, pos lspPosition) int {
+	i := 0
+	for line := 0; line < pos.Line; line++ {
+		nl := strings.IndexByte(text[i:], '\n')
+		if nl < 0 {
+			return len(text)
+		}
+		i += nl + 1
+	}
+	for units := 0; units < pos.Character && i < len(text) && text[i] != '\n'; {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		units += len(utf16.Encode([]rune{r}))
+		i += size
+	}
+	return i
+}
+
+// docPosition is the reverse of docOffset.
+func docPosition(text string, off int) (pos lspPosition) {
+	lineStart := strings.LastIndexByte(text[:off], '\n') + 1
+	pos.Line = strings.Count(text[:lineStart], "\n")
+	pos.Character = len(utf16.Encode([]rune(text[lineStart:off])))
+	return
+}
+
+// lineIndent is the leading white space of the line of off.
+func lineIndent(text string, off int) string {
+	start := strings.LastIndexByte(text[:off], '\n') + 1
+	line := text[start:]
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// indentLines indents all but the first line of code, which continues the
+// line of the selection.
+func indentLines(code string, indent string) string {
+	lines := strings.Split(code, "\n")
+	for i := 1; i < len(lines); i++ {
+		if len(lines[i]) > 0 {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,202 @@
+package parse_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+// lspClient speaks JSON-RPC with a LanguageServer over pipes.
+type lspClient struct {
+	t      *testing.T
+	w      io.WriteCloser
+	r      *bufio.Reader
+	nextID int
+}
+
+func (c *lspClient) send(id int, method string, params interface{}) {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		msg["id"] = id
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	_, err = fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *lspClient) notify(method string, params interface{}) {
+	c.send(0, method, params)
+}
+
+// call sends a request and decodes the result of its response into result.
+func (c *lspClient) call(method string, params interface{}, result interface{}) (rpcErr map[string]interface{}) {
+	c.nextID++
+	c.send(c.nextID, method, params)
+
+	length := 0
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			break
+		}
+		length, _ = strconv.Atoi(strings.TrimPrefix(line, "Content-Length: "))
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(c.r, body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	var resp struct {
+		ID     int
+		Result json.RawMessage
+		Error  map[string]interface{}
+	}
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if resp.ID != c.nextID {
+		c.t.Fatalf("response %d to request %d", resp.ID, c.nextID)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil {
+		err = json.Unmarshal(resp.Result, result)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return nil
+}
+
+type lspEdit struct {
+	Range struct {
+		Start struct{ Line, Character int }
+		End   struct{ Line, Character int }
+	}
+	NewText string
+}
+
+type lspAction struct {
+	Title string
+	Edit  struct {
+		Changes map[string][]lspEdit
+	}
+}
+
+func TestLanguageServer(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	s := &parse.LanguageServer{Converter: parse.NewConverter(parse.Options{Pkg: "h"})}
+	done := make(chan error)
+	go func() {
+		done <- s.Serve(inR, outW)
+	}()
+	c := &lspClient{t: t, w: inW, r: bufio.NewReader(outR)}
+
+	var init struct {
+		Capabilities struct {
+			CodeActionProvider interface{}
+		}
+	}
+	c.call("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, &init)
+	if init.Capabilities.CodeActionProvider == nil {
+		t.Fatal("no code action provider")
+	}
+	c.notify("initialized", map[string]interface{}{})
+
+	uri := "file:///page.go"
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "go", "version": 1, "text": "package page\n"},
+	})
+	doc := "package page\n\nfunc Page() h.HTMLComponent {\n\treturn h.Div(\n\t\t<p class=\"é\">Hi <b>you</b></p>\n\t)\n}\n"
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]interface{}{{"text": doc}},
+	})
+
+	codeActions := func(startLine, startChar, endLine, endChar int) (r []lspAction) {
+		c.call("textDocument/codeAction", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri},
+			"range": map[string]interface{}{
+				"start": map[string]int{"line": startLine, "character": startChar},
+				"end":   map[string]int{"line": endLine, "character": endChar},
+			},
+			"context": map[string]interface{}{"diagnostics": []interface{}{}},
+		}, &r)
+		return
+	}
+
+	// é is one UTF-16 character but two bytes
+	actions := codeActions(4, 2, 4, 33)
+	if len(actions) != 1 || actions[0].Title != "Convert selected HTML to htmlgo" {
+		t.Fatalf("actions: %#v", actions)
+	}
+	edits := actions[0].Edit.Changes[uri]
+	expected := "h.P(\n\t\t\th.Text(\"Hi\"),\n\t\t\th.B(\"you\"),\n\t\t).Class(\"é\")"
+	diff := testingutils.PrettyJsonDiff(expected, edits[0].NewText)
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+	if edits[0].Range.Start.Character != 2 || edits[0].Range.End.Character != 33 {
+		t.Errorf("range: %#v", edits[0].Range)
+	}
+
+	doc = "package page\n\nvar n = h.Div(h.H1(\"Hi\")).Class(\"title\")\n"
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 3},
+		"contentChanges": []map[string]interface{}{{"text": doc}},
+	})
+	actions = codeActions(2, 8, 2, 40)
+	if len(actions) != 1 || actions[0].Title != "Convert htmlgo expression to HTML" {
+		t.Fatalf("actions: %#v", actions)
+	}
+	expected = "<div class=\"title\">\n  <h1>Hi</h1>\n</div>"
+	diff = testingutils.PrettyJsonDiff(expected, actions[0].Edit.Changes[uri][0].NewText)
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+
+	if actions := codeActions(0, 0, 0, 7); len(actions) != 0 {
+		t.Errorf("actions for package: %#v", actions)
+	}
+
+	rpcErr := c.call("textDocument/codeAction", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"range": map[string]interface{}{
+			"start": map[string]int{"line": 2, "character": 10},
+			"end":   map[string]int{"line": 2, "character": 5},
+		},
+	}, nil)
+	if rpcErr == nil || rpcErr["code"] != float64(-32602) {
+		t.Errorf("error for a reversed range: %#v", rpcErr)
+	}
+
+	if rpcErr := c.call("textDocument/hover", map[string]interface{}{}, nil); rpcErr == nil {
+		t.Error("no error for an unknown method")
+	}
+
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	err := <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,135 @@
+package parse
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"strings"
+)
+
+//go:generate go run gen_htmlgo_funcs.go
+
+// applyTagMappings sets the builders configured in cfg.Tags and cfg.Attrs,
+// or discovered in cfg.Packages, on the tree. Elements htmlgo has no
+// function for are built with Tag("name"), with a warning written to
+// cfg.Warnings.
+func applyTagMappings(fc *funcCall, cfg *Config) (err error) {
+	tags, err := discoverTagMappings(cfg)
+	if err != nil {
+		return
+	}
+	tm := &tagMapper{
+		tags:     tags,
+		attrs:    cfg.Attrs,
+		inputs:   cfg.Inputs,
+		exact:    cfg.exactStrings,
+		fallback: len(cfg.Target) == 0 || cfg.Target == TargetHTMLGo,
+		warnings: cfg.Warnings,
+		warned:   map[string]bool{},
+	}
+	tm.apply(fc)
+	return
+}
+
+type tagMapper struct {
+	tags     map[string]*TagMapping
+	attrs    map[string]string
+	inputs   map[string]string
+	exact    bool
+	fallback bool
+	warnings io.Writer
+	warned   map[string]bool
+}
+
+func (tm *tagMapper) apply(fc *funcCall) {
+	if len(fc.Tag) > 0 && len(fc.Name) > 0 {
+		m, ok := tm.tags[fc.Tag]
+		switch {
+		case ok:
+			fc.Func = m.Func
+			fc.Mapped = true
+			fc.TakeText = m.Text
+		case tm.fallback && !strings.Contains(htmlgoFuncs, "|"+fc.Name+"|"):
+			fc.TagCall = true
+			fc.TakeText = false
+			tm.warn(fc.Tag)
+		case tm.fallback:
+			applyConstructorArg(fc, tm.inputs, tm.exact)
+		}
+
+		attrs := fc.Attrs[:0:0]
+		for _, att := range fc.Attrs {
+			method, ok := tm.attrs[att.Key]
+			var typ string
+			if m != nil {
+				if mm, mok := m.Attrs[att.Key]; mok {
+					method, ok = mm, mok
+					typ = m.Types[att.Key]
+				}
+			}
+			if !ok {
+				if m != nil && m.NoAttr {
+					tm.warnAttr(m.Func, att.Key)
+					continue
+				}
+				attrs = append(attrs, att)
+				continue
+			}
+			attrs = append(attrs, att)
+			if fc.AttrMethods == nil {
+				fc.AttrMethods = map[string]string{}
+				fc.AttrTypes = map[string]string{}
+			}
+			fc.AttrMethods[att.Key] = method
+			fc.AttrTypes[att.Key] = typ
+		}
+		fc.Attrs = attrs
+	}
+
+	for _, c := range fc.Children {
+		tm.apply(c)
+	}
+	for _, c := range fc.Else {
+		tm.apply(c)
+	}
+}
+
+func (tm *tagMapper) warnAttr(builder string, attr string) {
+	key := builder + " " + attr
+	if tm.warnings == nil || tm.warned[key] {
+		return
+	}
+	tm.warned[key] = true
+	_, _ = fmt.Fprintf(tm.warnings, "warning: %s has no method for the %s attribute and no Attr method, dropped it\n", builder, attr)
+}
+
+func (tm *tagMapper) warn(tag string) {
+	if tm.warnings == nil || tm.warned[tag] {
+		return
+	}
+	tm.warned[tag] = true
+	_, _ = fmt.Fprintf(tm.warnings, "warning: htmlgo has no function for <%s>, generated Tag(%q)\n", tag, tag)
+}
+
+// typedAttrValue formats an attribute value as an argument of type typ.
+// Attributes without value are true for untyped methods, which mostly are
+// boolean flags like dense or outlined.
+func typedAttrValue(val string, typ string, exact bool) string {
+	switch typ {
+	case "bool":
+		if val == "false" {
+			return "false"
+		}
+		return "true"
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64":
+		if k := literalKind(strings.TrimPrefix(val, "-")); k == token.INT || k == token.FLOAT {
+			return val
+		}
+	case "":
+		if len(val) == 0 {
+			return "true"
+		}
+	}
+	return goString(val, exact)
+}
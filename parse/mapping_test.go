@@ -0,0 +1,125 @@
+package parse_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestTagMappings(t *testing.T) {
+	cfg := &parse.Config{
+		Tags: map[string]*parse.TagMapping{
+			"v-btn": {
+				Func:  "vuetify.VBtn",
+				Text:  true,
+				Attrs: map[string]string{"color": "Color", "dense": "Dense"},
+			},
+			"v-card": {
+				Func:  "vuetify.VCard",
+				Attrs: map[string]string{"flat": "Flat"},
+			},
+		},
+		Attrs: map[string]string{"x-show": "XShow"},
+	}
+
+	html := `
+<div x-show="open">
+  <v-btn color="primary" dense @click="save">Save</v-btn>
+  <v-card flat>
+    <v-card-title class="headline">Title</v-card-title>
+  </v-card>
+</div>
+`
+	gocode := parse.GenerateHTMLGoWithConfig("h", false, cfg, strings.NewReader(html))
+	expected := `package hello
+
+var n = h.Body(
+	h.Div(
+		vuetify.VBtn("Save").Color("primary").
+			Dense(true).
+			Attr("x-on:click", "save"),
+		vuetify.VCard(
+			h.Tag("v-card-title").Class("headline").
+				Children(
+					h.Text("Title"),
+				),
+		).Flat(true),
+	).XShow("open"),
+)
+`
+	diff := testingutils.PrettyJsonDiff(expected, gocode)
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+}
+
+func TestTagFallback(t *testing.T) {
+	warnings := bytes.NewBuffer(nil)
+	cfg := &parse.Config{Warnings: warnings}
+
+	html := `
+<main>
+  <search><input name="q"></search>
+  <search class="second"></search>
+  <dialog open><p>Hi</p></dialog>
+</main>
+`
+	gocode := parse.GenerateHTMLGoWithConfig("", false, cfg, strings.NewReader(html))
+	expected := `package hello
+
+var n = Body(
+	Main(
+		Tag("search").
+			Children(
+				Input("q"),
+			),
+		Tag("search").Class("second"),
+		Dialog(
+			P(
+				Text("Hi"),
+			),
+		).Attr("open", ""),
+	),
+)
+`
+	diff := testingutils.PrettyJsonDiff(expected, gocode)
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+
+	expectedWarnings := "warning: htmlgo has no function for <search>, generated Tag(\"search\")\n"
+	if warnings.String() != expectedWarnings {
+		t.Errorf("warnings: %q", warnings.String())
+	}
+}
+
+// TestHTMLGoFuncs checks that htmlgo_funcs.go lists the element functions of
+// the htmlgo the module requires.
+func TestHTMLGoFuncs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go generate")
+	}
+	out := filepath.Join(t.TempDir(), "htmlgo_funcs.go")
+	b, err := exec.Command("go", "run", "gen_htmlgo_funcs.go", "-o", out).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, b)
+	}
+	generated, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := ioutil.ReadFile("htmlgo_funcs.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := testingutils.PrettyJsonDiff(string(generated), string(current))
+	if len(diff) > 0 {
+		t.Errorf("htmlgo_funcs.go is out of date, run go generate:\n%s", diff)
+	}
+}
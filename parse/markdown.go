@@ -0,0 +1,927 @@
+package parse
+
+import (
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// GenerateHTMLGoFromMarkdown converts Markdown into htmlgo code. It supports
+// the CommonMark headings, paragraphs, block quotes, lists, code blocks,
+// thematic breaks, HTML blocks, emphasis, code spans, links, images and
+// reference definitions, and GitHub tables and strikethrough.
+func GenerateHTMLGoFromMarkdown(pkg string, childrenMode bool, cfg *Config, md io.Reader) string {
+	return mustGenerate(newConverter(pkg, childrenMode, cfg).ConvertMarkdown(md))
+}
+
+// ConvertMarkdown is GenerateHTMLGoFromMarkdown with the options of c. The
+// text is collapsed unless the options set another whitespace policy, so
+// that the spaces around emphasis and links are kept.
+func (c *Converter) ConvertMarkdown(md io.Reader) (r string, err error) {
+	if err = c.validate(); err != nil {
+		return
+	}
+	src, err := ioutil.ReadAll(md)
+	if err != nil {
+		return
+	}
+	cfg := c.opts.Config
+	if len(cfg.Whitespace) == 0 {
+		cfg.Whitespace = WhitespaceCollapse
+	}
+	fc := convert(markdownBody(string(src)), c.methodNames, nil, cfg.Whitespace)
+	// Markdown has no body
+	if cfg.DropImplicit {
+		fc.Implicit = true
+	}
+	return generate(fc, c.methodNames, c.opts.Pkg, c.opts.ChildrenMode, &cfg, ShapeVar)
+}
+
+var (
+	mdATXHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*))?$`)
+	mdClosingHashes = regexp.MustCompile(`(^|[ \t]+)#+[ \t]*$`)
+	mdThematicBreak = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdSetext        = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdFence         = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*(.*)$")
+	mdBlockQuote    = regexp.MustCompile(`^ {0,3}> ?`)
+	mdBullet        = regexp.MustCompile(`^( {0,3})([-+*])( +|$)`)
+	mdOrdered       = regexp.MustCompile(`^( {0,3})(\d{1,9})([.)])( +|$)`)
+	mdTableDelim    = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdRefDef        = regexp.MustCompile(`^ {0,3}\[((?:[^\]\\]|\\.)+)\]:[ \t]*(<[^>]*>|\S+)(?:[ \t]+("[^"]*"|'[^']*'|\([^)]*\)))?[ \t]*$`)
+	mdHTMLBlock     = regexp.MustCompile(`^ {0,3}<(/?)([a-zA-Z][a-zA-Z0-9-]*)([ \t/>]|$)`)
+	mdAutolink      = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^ \t\n<>]*)>`)
+	mdEmailAutolink = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9.-]*[a-zA-Z0-9])?)>`)
+	mdEntity        = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+)
+
+// mdBlockTags start HTML blocks that end at a blank line, mdRawTags those
+// that end with their end tag.
+const (
+	mdBlockTags = "|address|article|aside|blockquote|body|details|dialog|dd|div|dl|dt|fieldset|figcaption|figure|" +
+		"footer|form|h1|h2|h3|h4|h5|h6|header|hr|iframe|li|main|nav|ol|p|section|summary|table|tbody|td|tfoot|" +
+		"th|thead|tr|ul|"
+	mdRawTags = "|pre|script|style|textarea|"
+)
+
+// markdownParser builds the HTML nodes of Markdown. The blocks are parsed
+// first, the inline content of their text once all the link reference
+// definitions are known.
+type markdownParser struct {
+	refs    map[string]markdownLink
+	inlines []markdownInline
+	// unwrap are the paragraphs of tight lists, replaced by their content
+	unwrap []*html.Node
+}
+
+type markdownLink struct {
+	dest  string
+	title string
+}
+
+// markdownInline is text whose inline content goes into n.
+type markdownInline struct {
+	n    *html.Node
+	text string
+}
+
+// markdownBody returns the body with the HTML of the Markdown src.
+func markdownBody(src string) *html.Node {
+	p := &markdownParser{refs: map[string]markdownLink{}}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	p.blocks(strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n"), body)
+
+	for _, in := range p.inlines {
+		p.inline(in.text, in.n)
+	}
+	for _, n := range p.unwrap {
+		for c := n.FirstChild; c != nil; c = n.FirstChild {
+			n.RemoveChild(c)
+			n.Parent.InsertBefore(c, n)
+		}
+		n.Parent.RemoveChild(n)
+	}
+	return body
+}
+
+func isBlank(line string) bool {
+	return len(strings.TrimSpace(line)) == 0
+}
+
+// indentOf is the column the text of line starts at, tabs advance to the
+// next multiple of 4.
+func indentOf(line string) (col int) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - col%4
+		default:
+			return
+		}
+	}
+	return
+}
+
+// removeIndent removes up to n columns of indentation, keeping the columns
+// of a tab beyond n as spaces.
+func removeIndent(line string, n int) string {
+	col := 0
+	for i := 0; i < len(line); i++ {
+		if col >= n {
+			return line[i:]
+		}
+		switch line[i] {
+		case ' ':
+			col++
+		case '\t':
+			next := col + 4 - col%4
+			if next > n {
+				return strings.Repeat(" ", next-n) + line[i+1:]
+			}
+			col = next
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+func element(parent *html.Node, tag string, attrs ...html.Attribute) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag)), Attr: attrs}
+	parent.AppendChild(n)
+	return n
+}
+
+func appendText(parent *html.Node, text string) {
+	if len(text) == 0 {
+		return
+	}
+	if c := parent.LastChild; c != nil && c.Type == html.TextNode {
+		c.Data += text
+		return
+	}
+	parent.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+}
+
+// blocks appends the blocks of lines to parent.
+func (p *markdownParser) blocks(lines []string, parent *html.Node) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case indentOf(line) >= 4:
+			i = p.indentedCode(lines, i, parent)
+		case mdFence.MatchString(line) && p.fence(line):
+			i = p.fencedCode(lines, i, parent)
+		case mdATXHeading.MatchString(line):
+			m := mdATXHeading.FindStringSubmatch(line)
+			text := mdClosingHashes.ReplaceAllString(strings.TrimSpace(m[2]), "")
+			p.inlines = append(p.inlines, markdownInline{element(parent, "h"+strconv.Itoa(len(m[1]))), text})
+			i++
+		case mdThematicBreak.MatchString(line):
+			element(parent, "hr")
+			i++
+		case mdBlockQuote.MatchString(line):
+			i = p.blockQuote(lines, i, parent)
+		case p.listItem(line, false):
+			i = p.list(lines, i, parent)
+		case p.htmlBlock(line, false):
+			i = p.rawHTML(lines, i, parent)
+		case p.tableStart(lines, i):
+			i = p.table(lines, i, parent)
+		default:
+			i = p.paragraph(lines, i, parent)
+		}
+	}
+}
+
+// fence reports whether the fence line opens a code block, the info string
+// of backtick fences has no backticks.
+func (p *markdownParser) fence(line string) bool {
+	m := mdFence.FindStringSubmatch(line)
+	return m[2][0] == '~' || !strings.Contains(m[3], "`")
+}
+
+// interrupts reports whether line starts a block that ends a paragraph.
+func (p *markdownParser) interrupts(line string) bool {
+	return (mdFence.MatchString(line) && p.fence(line)) ||
+		mdATXHeading.MatchString(line) ||
+		mdThematicBreak.MatchString(line) ||
+		mdBlockQuote.MatchString(line) ||
+		p.listItem(line, true) ||
+		p.htmlBlock(line, true)
+}
+
+func (p *markdownParser) indentedCode(lines []string, i int, parent *html.Node) int {
+	var code []string
+	for ; i < len(lines) && (isBlank(lines[i]) || indentOf(lines[i]) >= 4); i++ {
+		code = append(code, removeIndent(lines[i], 4))
+	}
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+	}
+	codeBlock(parent, strings.Join(code, "\n")+"\n", "")
+	return i
+}
+
+func (p *markdownParser) fencedCode(lines []string, i int, parent *html.Node) int {
+	m := mdFence.FindStringSubmatch(lines[i])
+	indent, fence := len(m[1]), m[2]
+	lang := ""
+	if fields := strings.Fields(m[3]); len(fields) > 0 {
+		lang = html.UnescapeString(fields[0])
+	}
+	var code []string
+	for i++; i < len(lines); i++ {
+		l := strings.TrimSpace(lines[i])
+		if indentOf(lines[i]) < 4 && strings.HasPrefix(l, fence) && strings.Trim(l, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, removeIndent(lines[i], indent))
+	}
+	text := strings.Join(code, "\n")
+	if len(code) > 0 {
+		text += "\n"
+	}
+	codeBlock(parent, text, lang)
+	return i
+}
+
+func codeBlock(parent *html.Node, code string, lang string) {
+	var attrs []html.Attribute
+	if len(lang) > 0 {
+		attrs = append(attrs, html.Attribute{Key: "class", Val: "language-" + lang})
+	}
+	appendText(element(element(parent, "pre"), "code", attrs...), code)
+}
+
+func (p *markdownParser) blockQuote(lines []string, i int, parent *html.Node) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if loc := mdBlockQuote.FindStringIndex(l); loc != nil {
+			inner = append(inner, l[loc[1]:])
+			continue
+		}
+		// lazy continuation of a paragraph
+		if isBlank(l) || len(inner) == 0 || isBlank(inner[len(inner)-1]) || p.interrupts(l) {
+			break
+		}
+		inner = append(inner, l)
+	}
+	p.blocks(inner, element(parent, "blockquote"))
+	return i
+}
+
+// listMarker is the start of a list item.
+type listMarker struct {
+	ordered bool
+	// delim is the bullet or the delimiter after the number.
+	delim byte
+	start int
+	// indent is the column of the content of the item.
+	indent int
+	empty  bool
+}
+
+func parseListMarker(line string) (m listMarker, ok bool) {
+	var width int
+	var spaces string
+	if s := mdBullet.FindStringSubmatch(line); s != nil {
+		m.delim = s[2][0]
+		width, spaces = len(s[1])+1, s[3]
+	} else if s := mdOrdered.FindStringSubmatch(line); s != nil {
+		m.ordered = true
+		m.delim = s[3][0]
+		m.start, _ = strconv.Atoi(s[2])
+		width, spaces = len(s[1])+len(s[2])+1, s[4]
+	} else {
+		return
+	}
+	m.empty = isBlank(line[width:])
+	// the content starts after one space if it's an indented code block
+	m.indent = width + len(spaces)
+	if m.empty || len(spaces) > 4 {
+		m.indent = width + 1
+	}
+	return m, true
+}
+
+// listItem reports whether line starts a list item, which only interrupts a
+// paragraph if it isn't empty and its number is 1.
+func (p *markdownParser) listItem(line string, interrupting bool) bool {
+	m, ok := parseListMarker(line)
+	if !ok || mdThematicBreak.MatchString(line) {
+		return false
+	}
+	return !interrupting || !m.empty && (!m.ordered || m.start == 1)
+}
+
+func (p *markdownParser) list(lines []string, i int, parent *html.Node) int {
+	first, _ := parseListMarker(lines[i])
+	tag := "ul"
+	var attrs []html.Attribute
+	if first.ordered {
+		tag = "ol"
+		if first.start != 1 {
+			attrs = append(attrs, html.Attribute{Key: "start", Val: strconv.Itoa(first.start)})
+		}
+	}
+	list := element(parent, tag, attrs...)
+
+	var items [][]string
+	loose := false
+	for i < len(lines) {
+		m, ok := parseListMarker(lines[i])
+		if !ok || m.ordered != first.ordered || m.delim != first.delim || mdThematicBreak.MatchString(lines[i]) {
+			break
+		}
+		item := []string{""}
+		if !m.empty {
+			item[0] = lines[i][m.indent:]
+		}
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			switch {
+			case isBlank(l):
+				item = append(item, "")
+				continue
+			case indentOf(l) >= m.indent:
+				item = append(item, removeIndent(l, m.indent))
+				continue
+			case p.listItem(l, false):
+				// the next item
+			case !isBlank(item[len(item)-1]) && !p.interrupts(l) && !mdSetext.MatchString(l):
+				// lazy continuation of a paragraph
+				item = append(item, l)
+				continue
+			}
+			break
+		}
+
+		blank := 0
+		for len(item) > 1 && isBlank(item[len(item)-1]) {
+			item = item[:len(item)-1]
+			blank++
+		}
+		if next, ok := parseListMarker(lineAt(lines, i)); blank > 0 && ok && next.ordered == first.ordered && next.delim == first.delim {
+			loose = true
+		}
+		items = append(items, item)
+	}
+
+	for _, item := range items {
+		li := element(list, "li")
+		p.blocks(item, li)
+		// a blank line between two blocks of the item makes the list loose
+		for j := 1; j < len(item) && li.FirstChild != li.LastChild; j++ {
+			if isBlank(item[j]) && !isBlank(item[j-1]) {
+				loose = true
+			}
+		}
+	}
+	if !loose {
+		for li := list.FirstChild; li != nil; li = li.NextSibling {
+			for c := li.FirstChild; c != nil; c = c.NextSibling {
+				if c.Data == "p" {
+					p.unwrap = append(p.unwrap, c)
+				}
+			}
+		}
+	}
+	return i
+}
+
+// lineAt is lines[i], empty after the last line.
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
+
+// htmlBlock reports whether line starts an HTML block. Only block tags
+// interrupt a paragraph.
+func (p *markdownParser) htmlBlock(line string, interrupting bool) bool {
+	if strings.HasPrefix(strings.TrimLeft(line, " "), "<!--") {
+		return true
+	}
+	m := mdHTMLBlock.FindStringSubmatch(line)
+	if m == nil {
+		return false
+	}
+	tag := "|" + strings.ToLower(m[2]) + "|"
+	if strings.Contains(mdBlockTags, tag) || strings.Contains(mdRawTags, tag) && len(m[1]) == 0 {
+		return true
+	}
+	// other tags only start a block when they are alone on the line
+	return !interrupting && strings.HasSuffix(strings.TrimSpace(line), ">") && strings.Count(line, "<") == 1
+}
+
+func (p *markdownParser) rawHTML(lines []string, i int, parent *html.Node) int {
+	var block []string
+	end := ""
+	if m := mdHTMLBlock.FindStringSubmatch(lines[i]); m != nil && strings.Contains(mdRawTags, "|"+strings.ToLower(m[2])+"|") {
+		end = "</" + strings.ToLower(m[2]) + ">"
+	} else if strings.HasPrefix(strings.TrimLeft(lines[i], " "), "<!--") {
+		end = "-->"
+	}
+	for ; i < len(lines); i++ {
+		if len(end) == 0 && isBlank(lines[i]) {
+			break
+		}
+		block = append(block, lines[i])
+		if len(end) > 0 && strings.Contains(strings.ToLower(lines[i]), end) {
+			i++
+			break
+		}
+	}
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(strings.Join(block, "\n")), context)
+	if err != nil {
+		appendText(element(parent, "p"), strings.Join(block, "\n"))
+		return i
+	}
+	for _, n := range nodes {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+		parent.AppendChild(n)
+	}
+	return i
+}
+
+// tableStart reports whether lines[i] is the header row of a table, followed
+// by the delimiter row with as many cells.
+func (p *markdownParser) tableStart(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.Contains(lines[i], "|") && strings.Contains(lines[i+1], "|") &&
+		mdTableDelim.MatchString(lines[i+1]) &&
+		len(splitRow(lines[i])) == len(splitRow(lines[i+1]))
+}
+
+func (p *markdownParser) table(lines []string, i int, parent *html.Node) int {
+	header := splitRow(lines[i])
+	var aligns []string
+	for _, d := range splitRow(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(d, ":") && strings.HasSuffix(d, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasPrefix(d, ":"):
+			aligns = append(aligns, "left")
+		case strings.HasSuffix(d, ":"):
+			aligns = append(aligns, "right")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+
+	table := element(parent, "table")
+	row := func(section *html.Node, cells []string, tag string) {
+		tr := element(section, "tr")
+		for j := range header {
+			var attrs []html.Attribute
+			if len(aligns[j]) > 0 {
+				attrs = append(attrs, html.Attribute{Key: "align", Val: aligns[j]})
+			}
+			text := ""
+			if j < len(cells) {
+				text = cells[j]
+			}
+			p.inlines = append(p.inlines, markdownInline{element(tr, tag, attrs...), text})
+		}
+	}
+	row(element(table, "thead"), header, "th")
+
+	var body *html.Node
+	for i += 2; i < len(lines) && !isBlank(lines[i]) && !p.interrupts(lines[i]); i++ {
+		if body == nil {
+			body = element(table, "tbody")
+		}
+		row(body, splitRow(lines[i]), "td")
+	}
+	return i
+}
+
+// splitRow returns the trimmed cells of a table row, split at the pipes that
+// aren't escaped.
+func splitRow(line string) (cells []string) {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// paragraph appends the paragraph or setext heading starting at lines[i],
+// after the link reference definitions it starts with.
+func (p *markdownParser) paragraph(lines []string, i int, parent *html.Node) int {
+	text := []string{strings.TrimLeft(lines[i], " \t")}
+	tag := "p"
+	for i++; i < len(lines) && !isBlank(lines[i]); i++ {
+		if m := mdSetext.FindStringSubmatch(lines[i]); m != nil {
+			tag = "h2"
+			if m[1][0] == '=' {
+				tag = "h1"
+			}
+			i++
+			break
+		}
+		if p.interrupts(lines[i]) {
+			break
+		}
+		text = append(text, strings.TrimLeft(lines[i], " \t"))
+	}
+
+	for len(text) > 0 {
+		m := mdRefDef.FindStringSubmatch(text[0])
+		if m == nil {
+			break
+		}
+		label := normalizeLabel(m[1])
+		if _, ok := p.refs[label]; !ok {
+			title := m[3]
+			if len(title) > 0 {
+				title = unescapeMarkdown(title[1 : len(title)-1])
+			}
+			p.refs[label] = markdownLink{dest: unescapeMarkdown(strings.Trim(m[2], "<>")), title: title}
+		}
+		text = text[1:]
+	}
+	if len(text) > 0 {
+		p.inlines = append(p.inlines, markdownInline{element(parent, tag), strings.TrimRight(strings.Join(text, "\n"), " ")})
+	}
+	return i
+}
+
+// normalizeLabel is the case and white space insensitive key of a link
+// label.
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("$+<=>^`|~", c) >= 0
+}
+
+// unescapeMarkdown removes the backslashes of escaped punctuation and
+// decodes entities.
+func unescapeMarkdown(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return html.UnescapeString(b.String())
+}
+
+// inline appends the nodes of the inline content text to parent.
+func (p *markdownParser) inline(text string, parent *html.Node) {
+	var buf strings.Builder
+	flush := func() {
+		appendText(parent, buf.String())
+		buf.Reset()
+	}
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			flush()
+			element(parent, "br")
+			i += 2
+		case c == '\\' && i+1 < len(text) && isPunct(text[i+1]):
+			buf.WriteByte(text[i+1])
+			i += 2
+		case c == '\n':
+			// two spaces at the end of a line break it, other line breaks
+			// render as a space
+			s := buf.String()
+			trimmed := strings.TrimRight(s, " ")
+			buf.Reset()
+			buf.WriteString(trimmed)
+			if len(s)-len(trimmed) >= 2 {
+				flush()
+				element(parent, "br")
+			} else {
+				buf.WriteByte(' ')
+			}
+			for i++; i < len(text) && text[i] == ' '; i++ {
+			}
+		case c == '`':
+			n := runLength(text, i)
+			end := closingBackticks(text, i+n, n)
+			if end < 0 {
+				buf.WriteString(text[i : i+n])
+				i += n
+				continue
+			}
+			flush()
+			appendText(element(parent, "code"), codeSpan(text[i+n:end]))
+			i = end + n
+		case c == '&':
+			if m := mdEntity.FindString(text[i:]); len(m) > 0 {
+				buf.WriteString(html.UnescapeString(m))
+				i += len(m)
+				continue
+			}
+			buf.WriteByte(c)
+			i++
+		case c == '<':
+			if m := mdAutolink.FindStringSubmatch(text[i:]); m != nil {
+				flush()
+				appendText(element(parent, "a", html.Attribute{Key: "href", Val: m[1]}), m[1])
+				i += len(m[0])
+				continue
+			}
+			if m := mdEmailAutolink.FindStringSubmatch(text[i:]); m != nil {
+				flush()
+				appendText(element(parent, "a", html.Attribute{Key: "href", Val: "mailto:" + m[1]}), m[1])
+				i += len(m[0])
+				continue
+			}
+			buf.WriteByte(c)
+			i++
+		case c == '[' || c == '!' && i+1 < len(text) && text[i+1] == '[':
+			image := c == '!'
+			start := i
+			if image {
+				start++
+			}
+			label, link, end, ok := p.link(text, start)
+			if !ok {
+				buf.WriteString(text[i : start+1])
+				i = start + 1
+				continue
+			}
+			flush()
+			if image {
+				alt := &html.Node{Type: html.ElementNode}
+				p.inline(label, alt)
+				attrs := []html.Attribute{{Key: "src", Val: link.dest}, {Key: "alt", Val: textContent(alt)}}
+				if len(link.title) > 0 {
+					attrs = append(attrs, html.Attribute{Key: "title", Val: link.title})
+				}
+				element(parent, "img", attrs...)
+			} else {
+				attrs := []html.Attribute{{Key: "href", Val: link.dest}}
+				if len(link.title) > 0 {
+					attrs = append(attrs, html.Attribute{Key: "title", Val: link.title})
+				}
+				p.inline(label, element(parent, "a", attrs...))
+			}
+			i = end
+		case c == '*' || c == '_' || c == '~' && strings.HasPrefix(text[i:], "~~"):
+			n := runLength(text, i)
+			if c == '~' {
+				n = 2
+			} else if n > 3 {
+				n = 3
+			}
+			end := p.closingDelimiter(text, i, n)
+			if end < 0 {
+				buf.WriteByte(c)
+				i++
+				continue
+			}
+			flush()
+			var el *html.Node
+			switch {
+			case c == '~':
+				el = element(parent, "del")
+			case n == 1:
+				el = element(parent, "em")
+			case n == 2:
+				el = element(parent, "strong")
+			default:
+				el = element(element(parent, "em"), "strong")
+			}
+			p.inline(text[i+n:end], el)
+			i = end + n
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+}
+
+// runLength is the number of times the character at i repeats.
+func runLength(text string, i int) (n int) {
+	for n = 1; i+n < len(text) && text[i+n] == text[i]; n++ {
+	}
+	return
+}
+
+// closingBackticks returns the start of the next run of exactly n backticks
+// from i, -1 if there is none.
+func closingBackticks(text string, i int, n int) int {
+	for i < len(text) {
+		j := strings.IndexByte(text[i:], '`')
+		if j < 0 {
+			return -1
+		}
+		j += i
+		run := runLength(text, j)
+		if run == n {
+			return j
+		}
+		i = j + run
+	}
+	return -1
+}
+
+// codeSpan turns line breaks into spaces and strips one space on both sides.
+func codeSpan(code string) string {
+	code = strings.ReplaceAll(code, "\n", " ")
+	if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && len(strings.TrimSpace(code)) > 0 {
+		code = code[1 : len(code)-1]
+	}
+	return code
+}
+
+// closingDelimiter returns the start of the run of n delimiters closing the
+// one at i, -1 if it doesn't open emphasis or isn't closed. Intraword
+// underscores don't open or close emphasis.
+func (p *markdownParser) closingDelimiter(text string, i int, n int) int {
+	c := text[i]
+	after, _ := utf8.DecodeRuneInString(text[i+n:])
+	before, _ := utf8.DecodeLastRuneInString(text[:i])
+	if i+n >= len(text) || unicode.IsSpace(after) || c == '_' && isWordChar(before) {
+		return -1
+	}
+	for j := i + n; j < len(text); {
+		switch text[j] {
+		case '`':
+			run := runLength(text, j)
+			if end := closingBackticks(text, j+run, run); end >= 0 {
+				j = end + run
+				continue
+			}
+			j += run
+			continue
+		case '\\':
+			j += 2
+			continue
+		case c:
+		default:
+			j++
+			continue
+		}
+		run := runLength(text, j)
+		prev, _ := utf8.DecodeLastRuneInString(text[:j])
+		next, _ := utf8.DecodeRuneInString(text[j+run:])
+		closes := !unicode.IsSpace(prev) && (c != '_' || j+run == len(text) || !isWordChar(next))
+		if closes && (run == n || c == '~' && run >= n) {
+			return j
+		}
+		j += run
+	}
+	return -1
+}
+
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// link parses the link or image label at text[i], which is '[', followed
+// by an inline destination or a reference. It returns the label, the link
+// and the end of the link in text.
+func (p *markdownParser) link(text string, i int) (label string, link markdownLink, end int, ok bool) {
+	depth := 0
+	closing := -1
+	for j := i; j < len(text) && closing < 0; j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '`':
+			run := runLength(text, j)
+			if e := closingBackticks(text, j+run, run); e >= 0 {
+				j = e + run - 1
+			} else {
+				j += run - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = j
+			}
+		}
+	}
+	if closing < 0 {
+		return
+	}
+	label = text[i+1 : closing]
+	end = closing + 1
+
+	if end < len(text) && text[end] == '(' {
+		if l, e, inline := inlineLink(text, end); inline {
+			return label, l, e, true
+		}
+	}
+	ref := label
+	if strings.HasPrefix(text[end:], "[") {
+		if e := strings.IndexByte(text[end:], ']'); e >= 0 {
+			if r := text[end+1 : end+e]; len(strings.TrimSpace(r)) > 0 {
+				ref = r
+			}
+			end += e + 1
+		}
+	}
+	link, ok = p.refs[normalizeLabel(ref)]
+	if !ok {
+		// a shortcut reference that isn't defined
+		end = closing + 1
+	}
+	return
+}
+
+// inlineLink parses the destination and title in parentheses at text[i].
+func inlineLink(text string, i int) (link markdownLink, end int, ok bool) {
+	j := i + 1
+	skipSpace := func() {
+		for j < len(text) && (text[j] == ' ' || text[j] == '\n') {
+			j++
+		}
+	}
+	skipSpace()
+	if j < len(text) && text[j] == '<' {
+		e := strings.IndexAny(text[j:], ">\n")
+		if e < 0 || text[j+e] != '>' {
+			return
+		}
+		link.dest = unescapeMarkdown(text[j+1 : j+e])
+		j += e + 1
+	} else {
+		start, depth := j, 0
+		for ; j < len(text) && text[j] > ' '; j++ {
+			if text[j] == '\\' && j+1 < len(text) && isPunct(text[j+1]) {
+				j++
+			} else if text[j] == '(' {
+				depth++
+			} else if text[j] == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+		link.dest = unescapeMarkdown(text[start:j])
+	}
+	skipSpace()
+	if j < len(text) && strings.IndexByte(`"'(`, text[j]) >= 0 {
+		closing := text[j]
+		if closing == '(' {
+			closing = ')'
+		}
+		e := strings.IndexByte(text[j+1:], closing)
+		if e < 0 {
+			return
+		}
+		link.title = unescapeMarkdown(text[j+1 : j+1+e])
+		j += e + 2
+		skipSpace()
+	}
+	if j >= len(text) || text[j] != ')' {
+		return
+	}
+	return link, j + 1, true
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
@@ -0,0 +1,212 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestGenerateHTMLGoFromMarkdown(t *testing.T) {
+	var cases = []struct {
+		name   string
+		pkg    string
+		cfg    *parse.Config
+		md     string
+		gocode string
+	}{
+		{
+			name: "headings and inlines",
+			md: "# Help *center* #\n\n" +
+				"Some **bold**, _em_ and ***both***, snake_case_name, `a * b` and ~~gone~~.  \n" +
+				"Next &amp; \\*line\\*\nend\n\n" +
+				"Setext\n======\n",
+			gocode: `package hello
+
+var n = Body(
+	H1("").
+		Children(
+			Text("Help "),
+			Em("center"),
+		),
+	P(
+		Text("Some "),
+		Strong("bold"),
+		Text(", "),
+		Em("em"),
+		Text(" and "),
+		Em("").
+			Children(
+				Strong("both"),
+			),
+		Text(", snake_case_name, "),
+		Code("a * b"),
+		Text(" and "),
+		Del("gone"),
+		Text("."),
+		Br(),
+		Text("Next & *line* end"),
+	),
+	H1("Setext"),
+)
+`,
+		},
+		{
+			name: "lists",
+			md:   "- one\n- two\n  1. nested\n  2. list\n- three\n\n---\n\n3. loose\n\n4. list\n",
+			gocode: `package hello
+
+var n = Body(
+	Ul(
+		Li(
+			Text("one"),
+		),
+		Li(
+			Text("two"),
+			Ol(
+				Li(
+					Text("nested"),
+				),
+				Li(
+					Text("list"),
+				),
+			),
+		),
+		Li(
+			Text("three"),
+		),
+	),
+	Hr(),
+	Ol(
+		Li(
+			P(
+				Text("loose"),
+			),
+		),
+		Li(
+			P(
+				Text("list"),
+			),
+		),
+	).Attr("start", "3"),
+)
+`,
+		},
+		{
+			name: "code blocks",
+			md:   "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n\n    $ go run .\n",
+			gocode: `package hello
+
+var n = Body(
+	Pre("").
+		Children(
+			Code("func main() {\n\tfmt.Println(\"hi\")\n}\n").Class("language-go"),
+		),
+	Pre("").
+		Children(
+			Code("$ go run .\n"),
+		),
+)
+`,
+		},
+		{
+			name: "links and images",
+			md: "See [the docs](/docs \"Docs\"), [FAQ][faq], [faq] and <https://example.com>.\n\n" +
+				"![Logo *big*](/logo.png)\n\n" +
+				"[FAQ]: /faq\n",
+			gocode: `package hello
+
+var n = Body(
+	P(
+		Text("See "),
+		A(
+			Text("the docs"),
+		).Href("/docs").
+			Title("Docs"),
+		Text(", "),
+		A(
+			Text("FAQ"),
+		).Href("/faq"),
+		Text(", "),
+		A(
+			Text("faq"),
+		).Href("/faq"),
+		Text(" and "),
+		A(
+			Text("https://example.com"),
+		).Href("https://example.com"),
+		Text("."),
+	),
+	P(
+		Img("/logo.png").Alt("Logo big"),
+	),
+)
+`,
+		},
+		{
+			name: "tables and block quotes",
+			md:   "| Name | Age |\n|:-----|----:|\n| Bob  | 3 |\n| `a\\|b` |\n\n> Quote\ncontinued\n",
+			gocode: `package hello
+
+var n = Body(
+	Table(
+		Thead(
+			Tr(
+				Th("Name").Attr("align", "left"),
+				Th("Age").Attr("align", "right"),
+			),
+		),
+		Tbody(
+			Tr(
+				Td(
+					Text("Bob"),
+				).Attr("align", "left"),
+				Td(
+					Text("3"),
+				).Attr("align", "right"),
+			),
+			Tr(
+				Td(
+					Code("a|b"),
+				).Attr("align", "left"),
+				Td().Attr("align", "right"),
+			),
+		),
+	),
+	Blockquote(
+		P(
+			Text("Quote continued"),
+		),
+	),
+)
+`,
+		},
+		{
+			name: "drop implicit body",
+			pkg:  "h",
+			cfg:  &parse.Config{DropImplicit: true},
+			md:   "Hello *world*\n",
+			gocode: `package hello
+
+var n = h.Components(
+	h.P(
+		h.Text("Hello "),
+		h.Em("world"),
+	),
+)
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gocode := parse.GenerateHTMLGoFromMarkdown(c.pkg, false, c.cfg, strings.NewReader(c.md))
+			diff := testingutils.PrettyJsonDiff(c.gocode, gocode)
+
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
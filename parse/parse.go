@@ -4,57 +4,79 @@ import (
 	"bytes"
 	"fmt"
 	"go/ast"
-	"go/parser"
-	"go/printer"
-	"go/token"
 	"io"
+	"io/ioutil"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/iancoleman/strcase"
 	"github.com/theplant/htmlgo"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 func GenerateHTMLGo(pkg string, childrenMode bool, htmlCode io.Reader) string {
-	n, err := html.Parse(htmlCode)
+	return GenerateHTMLGoWithConfig(pkg, childrenMode, nil, htmlCode)
+}
+
+// GenerateHTMLGoWithConfig is GenerateHTMLGo with the output shape and names
+// taken from cfg.
+func GenerateHTMLGoWithConfig(pkg string, childrenMode bool, cfg *Config, htmlCode io.Reader) string {
+	return mustGenerate(newConverter(pkg, childrenMode, cfg).Convert(htmlCode))
+}
+
+var documentTag = regexp.MustCompile(`(?i)<(!doctype|html|head|body)[\s>]`)
+
+// parseBody parses htmlCode and returns its body. Snippets without document
+// tags are parsed in the body context, otherwise a leading <template>,
+// <script> or <style> would end up in the head.
+func parseBody(htmlCode io.Reader) (body *html.Node, err error) {
+	src, err := ioutil.ReadAll(htmlCode)
 	if err != nil {
-		panic(err)
+		return
 	}
-	methodNames := tagMethodNames()
-	fc := &funcCall{}
-	walk(n.FirstChild.FirstChild.NextSibling, fc, methodNames)
 
-	code := string(fc.MarshalCode(methodNames, pkg, childrenMode))
-	code = strings.TrimRight(code, ",\n")
+	body = &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	if !documentTag.Match(src) {
+		var nodes []*html.Node
+		nodes, err = html.ParseFragment(bytes.NewReader(src), body)
+		if err != nil {
+			return
+		}
+		for _, n := range nodes {
+			if n.Parent != nil {
+				n.Parent.RemoveChild(n)
+			}
+			body.AppendChild(n)
+		}
+		return
+	}
 
-	fset := token.NewFileSet()
-	var f *ast.File
-	f, err = parser.ParseFile(fset, "", "package hello\n var n = "+code, 0)
+	doc, err := html.Parse(bytes.NewReader(src))
 	if err != nil {
-		hl, _ := strconv.ParseInt(strings.Split(err.Error(), ":")[0], 10, 64)
-		panic(fmt.Sprintf("%s\n%s", err, codeWithLineNumber(code, hl)))
+		return
 	}
-	buf := bytes.NewBuffer(nil)
-	err = printer.Fprint(buf, fset, f)
-	if err != nil {
-		panic(err)
+	for n := doc.FirstChild; n != nil; n = n.NextSibling {
+		if n.DataAtom != atom.Html {
+			continue
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.DataAtom == atom.Body {
+				body = c
+			}
+		}
 	}
-	return buf.String()
+	return
 }
 
-func codeWithLineNumber(src string, highlightLine int64) (r string) {
-	lines := strings.Split(src, "\n")
-	linesWithNumber := []string{}
-	for i, l := range lines {
-		hl := "   "
-		if int64(i+1) == highlightLine {
-			hl = ">> "
-		}
-		linesWithNumber = append(linesWithNumber, fmt.Sprintf("%s%d: %s", hl, i+1, l))
+func convert(body *html.Node, methodNames []string, exprs []string, whitespace string) (fc *funcCall) {
+	fc = &funcCall{}
+	walk(body, fc, methodNames, whitespace)
+	if len(exprs) > 0 {
+		expandTemplateExprs(fc, exprs)
 	}
-	r = strings.Join(linesWithNumber, "\n")
 	return
 }
 
@@ -66,102 +88,261 @@ func pkgDot(pkg string) (r string) {
 }
 
 type funcCall struct {
-	Pkg      string
-	Name     string
-	Text     string
-	TakeText bool
-	Children []*funcCall
-	Attrs    []html.Attribute
+	Pkg         string
+	Name        string
+	Tag         string
+	Func        string
+	Mapped      bool
+	TagCall     bool
+	Text        string
+	TextExpr    string
+	TakeText    bool
+	Children    []*funcCall
+	Attrs       []html.Attribute
+	AttrExprs   map[string]string
+	AttrMethods map[string]string
+	AttrTypes   map[string]string
+	Arg         string
+	ArgExpr     string
+	Consumed    []string
+	FuncName    string
+	TextParam   string
+	Params      []*funcParam
+	Implicit    bool
+	Line        int
+	Column      int
+
+	Expr       string
+	Cond       string
+	Lazy       bool
+	Else       []*funcCall
+	IsElse     bool
+	RangeExpr  string
+	RangeKey   string
+	RangeValue string
+}
+
+func (fc *funcCall) isText() bool {
+	return len(fc.Text) > 0 || len(fc.TextExpr) > 0
 }
 
-func (fc *funcCall) MarshalCode(methodNames []string, pkg string, childrenMode bool) (r []byte) {
+func (fc *funcCall) textCode(b *astBuilder) ast.Expr {
+	if len(fc.TextExpr) > 0 {
+		return b.expr(fc.TextExpr)
+	}
+	return b.str(fc.Text)
+}
+
+// MarshalCode writes the code of fc followed by ",\n" to b and returns its
+// expression.
+func (fc *funcCall) MarshalCode(b *astBuilder, methodNames []string, pkg string, childrenMode bool) (r ast.Expr) {
+	// the source comment goes at the end of the first line of the call
+	comment := fc.srcComment()
+	lineBreak := func() {
+		if len(comment) > 0 {
+			b.comment(comment)
+			comment = ""
+		}
+		b.write("\n")
+	}
+	defer func() {
+		b.write(",")
+		lineBreak()
+	}()
+
+	if len(fc.FuncName) > 0 {
+		return fc.funcCallCode(b)
+	}
 
-	buf := bytes.NewBuffer(nil)
+	if fc.isText() {
+		call := b.call(b.name(pkgDot(pkg) + "Text"))
+		call.Args = append(call.Args, fc.textCode(b))
+		return b.end(call)
+	}
 
-	if len(fc.Text) > 0 {
-		buf.WriteString(fmt.Sprintf("%sText(%#+v),\n", pkgDot(pkg), fc.Text))
-		return buf.Bytes()
+	if len(fc.Expr) > 0 {
+		return b.expr(fc.Expr)
 	}
 
-	newline := "\n"
-	if fc.TakeText {
-		newline = ""
+	if len(fc.Cond) > 0 {
+		return fc.marshalIf(b, methodNames, pkg, childrenMode)
+	}
+
+	if len(fc.RangeExpr) > 0 {
+		return fc.marshalRange(b, methodNames, pkg, childrenMode)
+	}
+
+	if fc.Implicit {
+		call := b.call(b.name(pkgDot(pkg) + "Components"))
+		b.write("\n")
+		for _, c := range fc.Children {
+			call.Args = append(call.Args, c.MarshalCode(b, methodNames, pkg, childrenMode))
+		}
+		return b.end(call)
 	}
-	_, _ = fmt.Fprintf(buf, "%s%s(%s", pkgDot(pkg), strcase.ToCamel(fc.Name), newline)
 
 	needWriteChilren := false
-	if childrenMode {
+	var call *ast.CallExpr
+	if fc.TagCall {
+		call = b.call(b.name(pkgDot(pkg) + "Tag"))
+		call.Args = append(call.Args, b.str(fc.Tag))
+		b.end(call)
 		needWriteChilren = true
-		if fc.TakeText && len(fc.Children) == 1 && len(fc.Children[0].Text) > 0 {
-			buf.WriteString(fmt.Sprintf("%#+v", fc.Children[0].Text))
-			needWriteChilren = false
-		} else if fc.TakeText {
-			buf.WriteString(`""`)
-		}
 	} else {
-		if fc.TakeText && len(fc.Children) == 1 && len(fc.Children[0].Text) > 0 {
-			buf.WriteString(fmt.Sprintf("%#+v", fc.Children[0].Text))
-		} else if fc.TakeText {
-			buf.WriteString(`""`)
+		name := pkgDot(pkg) + strcase.ToCamel(fc.Name)
+		if len(fc.Func) > 0 {
+			name = fc.Func
+		}
+		call = b.call(b.name(name))
+		// an empty argument list stays on one line, with the source comment
+		// after it
+		hasArgs := len(fc.ArgExpr) > 0 || len(fc.Arg) > 0 || !childrenMode && len(fc.Children) > 0
+		if !fc.TakeText && hasArgs {
+			lineBreak()
+		}
+
+		if len(fc.ArgExpr) > 0 {
+			call.Args = append(call.Args, b.expr(fc.ArgExpr))
+			needWriteChilren = true
+		} else if len(fc.Arg) > 0 {
+			call.Args = append(call.Args, b.lit(fc.Arg))
+			needWriteChilren = true
+		} else if childrenMode {
 			needWriteChilren = true
+			if fc.TakeText && len(fc.Children) == 1 && fc.Children[0].isText() {
+				call.Args = append(call.Args, fc.Children[0].textCode(b))
+				needWriteChilren = false
+			} else if fc.TakeText {
+				call.Args = append(call.Args, b.str(""))
+			}
 		} else {
-			for _, c := range fc.Children {
-				buf.Write(c.MarshalCode(methodNames, pkg, childrenMode))
+			if fc.TakeText && len(fc.Children) == 1 && fc.Children[0].isText() {
+				call.Args = append(call.Args, fc.Children[0].textCode(b))
+			} else if fc.TakeText {
+				call.Args = append(call.Args, b.str(""))
+				needWriteChilren = true
+			} else {
+				for _, c := range fc.Children {
+					call.Args = append(call.Args, c.MarshalCode(b, methodNames, pkg, childrenMode))
+				}
 			}
 		}
+
+		b.end(call)
 	}
+	r = call
 
-	buf.WriteString(")")
-	for i, att := range fc.Attrs {
-		attFuncName := getFuncName(att.Key, methodNames)
+	typed := func(key string) bool {
+		_, ok := fc.AttrMethods[key]
+		return ok || len(fc.attrFuncName(key, methodNames)) > 0
+	}
+	i := 0
+	for _, att := range fc.orderedAttrs(b.attrOrder, typed) {
+		if fc.consumed(att.Key) {
+			continue
+		}
+		attFuncName := fc.attrFuncName(att.Key, methodNames)
+		if len(attFuncName) > 0 && strings.Contains(intAttr, "|"+attFuncName+"|") {
+			// values that aren't numbers, like template expressions, are
+			// set with Attr
+			if _, err := strconv.ParseInt(att.Val, 10, 64); err != nil {
+				attFuncName = ""
+			}
+		}
+		// htmlgo drops attributes whose value is empty, but renders the ones
+		// set to true without a value
+		_, isExpr := fc.AttrExprs[att.Key]
+		valueless := b.exactStrings && len(att.Val) == 0 && !isExpr
+		if valueless && !strings.Contains(boolAttr, "|"+attFuncName+"|") {
+			attFuncName = ""
+		}
 
-		buf.WriteString(".")
+		// gofmt misaligns the next line after a comment following a dot
+		b.write(".")
 		if i > 0 {
-			buf.WriteString("\n")
+			b.write("\n")
 		}
-
-		if len(attFuncName) > 0 {
-			var val interface{} = att.Val
+		i++
+
+		// val is a literal, expr an expression of the input
+		var method, val, expr string
+		isAttr := false
+		if m, ok := fc.AttrMethods[att.Key]; ok {
+			method = m
+			val = typedAttrValue(att.Val, fc.AttrTypes[att.Key], b.exactStrings)
+			expr = fc.AttrExprs[att.Key]
+		} else if len(attFuncName) > 0 {
+			method = attFuncName
+			var v interface{} = att.Val
 			if strings.Index(boolAttr, "|"+attFuncName+"|") >= 0 {
-				val = true
+				v = true
 			}
 			if strings.Index(intAttr, "|"+attFuncName+"|") >= 0 {
-				var err error
-				val, err = strconv.ParseInt(att.Val, 10, 64)
-				if err != nil {
-					panic(err)
-				}
+				v, _ = strconv.ParseInt(att.Val, 10, 64)
+			}
+			if s, ok := v.(string); ok {
+				val = goString(s, b.exactStrings)
+				expr = fc.AttrExprs[att.Key]
+			} else {
+				val = normalizeGoString(v).(string)
 			}
-			_, _ = fmt.Fprintf(buf, "%s(%s)", attFuncName, normalizeGoString(val))
 		} else {
-			_, _ = fmt.Fprintf(buf, "Attr(%#+v, %s)", expandAlpineKey(att.Key), normalizeGoString(att.Val))
+			method = "Attr"
+			isAttr = true
+			val = goString(att.Val, b.exactStrings)
+			expr = fc.AttrExprs[att.Key]
+			if valueless {
+				val = "true"
+			}
 		}
+
+		c := b.call(&ast.SelectorExpr{X: r, Sel: b.ident(method)})
+		if isAttr {
+			c.Args = append(c.Args, b.str(expandAlpineKey(att.Key)))
+			b.write(", ")
+		}
+		if len(expr) > 0 {
+			c.Args = append(c.Args, b.expr(expr))
+		} else {
+			c.Args = append(c.Args, b.lit(val))
+		}
+		r = b.end(c)
 	}
 
 	if needWriteChilren && len(fc.Children) > 0 {
-		buf.WriteString(".Children(\n")
-		for _, c := range fc.Children {
-			buf.Write(c.MarshalCode(methodNames, pkg, childrenMode))
+		b.write(".\n")
+		c := b.call(&ast.SelectorExpr{X: r, Sel: b.ident("Children")})
+		lineBreak()
+		for _, ch := range fc.Children {
+			c.Args = append(c.Args, ch.MarshalCode(b, methodNames, pkg, childrenMode))
 		}
-		buf.WriteString(")")
+		r = b.end(c)
 	}
 
-	buf.WriteString(",\n")
-
-	return buf.Bytes()
+	return
 }
 
+// expandAlpineKey writes the Alpine.js shorthands :attr and @event in their
+// long form, x-bind:attr and x-on:event.
 func expandAlpineKey(key string) (r string) {
 	if strings.Index(key, ":") == 0 {
 		return fmt.Sprintf("x-bind%s", key)
 	}
 
 	if strings.Index(key, "@") == 0 {
-		return fmt.Sprintf("x-on%s", key)
+		return fmt.Sprintf("x-on:%s", key[1:])
 	}
 	return key
 }
 
+// goString is the Go string literal of val, exactly val if exact is set.
+func goString(val string, exact bool) string {
+	if exact {
+		return strconv.Quote(val)
+	}
+	return normalizeGoString(val).(string)
+}
+
 func normalizeGoString(val interface{}) (r interface{}) {
 	strval, ok := val.(string)
 	if !ok {
@@ -186,24 +367,29 @@ const textTags = "|Abbr|B|Bdi|Bdo|Button|Caption|Code|Del|Dfn|Em|Figcaption|H1|H
 	"H6|I|Img|Input|Kbd|Label|Legend|Link|Mark|Object|Option|Param|Pre|Q|Rp|Rt|S|" +
 	"Script|Small|Source|Span|Strong|Style|Sub|Sup|Textarea|Th|Time|Title|Track|U|Var|Wbr|"
 
-func walk(n *html.Node, fc *funcCall, methodNames []string) {
+func walk(n *html.Node, fc *funcCall, methodNames []string, whitespace string) {
 	switch n.Type {
 	case html.ElementNode:
 		if len(strings.TrimSpace(n.Data)) > 0 {
-			fc.Name = strcase.ToCamel(strings.TrimSpace(n.Data))
+			fc.Tag = strings.TrimSpace(n.Data)
+			fc.Name = strcase.ToCamel(fc.Tag)
 		}
+		takeAnnotations(fc)
 	case html.TextNode:
-		if len(strings.TrimSpace(n.Data)) > 0 {
-			fc.Text = strings.TrimSpace(n.Data)
-		}
+		fc.Text = whitespaceText(n.Data, whitespace)
 	}
 
 	if strings.Index(textTags, "|"+fc.Name+"|") >= 0 {
 		fc.TakeText = true
 	}
+	// collapsing keeps the rendered text, which isn't collapsed in <pre>
+	// and <textarea>
+	if (fc.Tag == "pre" || fc.Tag == "textarea") && whitespace == WhitespaceCollapse {
+		whitespace = WhitespacePreserve
+	}
 
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if c.Type == html.TextNode && len(strings.TrimSpace(c.Data)) == 0 {
+		if c.Type == html.TextNode && len(whitespaceText(c.Data, whitespace)) == 0 {
 			continue
 		}
 		if c.Type == html.CommentNode {
@@ -212,8 +398,20 @@ func walk(n *html.Node, fc *funcCall, methodNames []string) {
 
 		ch := &funcCall{Attrs: c.Attr}
 		fc.Children = append(fc.Children, ch)
-		walk(c, ch, methodNames)
+		walk(c, ch, methodNames, whitespace)
+	}
+
+	bindTextParam(fc)
+	splitElse(fc)
+}
+
+// attrFuncName is the htmlgo method of the attribute, none for elements
+// built by a TagMapping, which only have the methods of the mapping.
+func (fc *funcCall) attrFuncName(key string, methodNames []string) string {
+	if fc.Mapped {
+		return ""
 	}
+	return getFuncName(key, methodNames)
 }
 
 func getFuncName(name string, methodNames []string) (r string) {
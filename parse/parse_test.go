@@ -510,6 +510,127 @@ var n = Body().
 					),
 			),
 	)
+`,
+		},
+		{
+			name: "extract annotated components into functions",
+			html: `
+<div class="list">
+  <div class="card" data-html2go-func="UserCard">
+    <a href="/u/1" data-html2go-param-url="href"><span data-html2go-param-name="text">Alice</span></a>
+    <p>Member</p>
+  </div>
+  <div class="card" data-html2go-func="UserCard">
+    <a href="/u/2" data-html2go-param-url="href"><span data-html2go-param-name="text">Bob</span></a>
+    <p>Member</p>
+  </div>
+</div>
+`,
+			gocode: `package hello
+
+var n = Body(
+	Div(
+		UserCard("/u/1", "Alice"),
+		UserCard("/u/2", "Bob"),
+	).Class("list"),
+)
+
+func UserCard(url string, name string) HTMLComponent {
+	return Div(
+		A(
+			Span(name),
+		).Href(url),
+		P(
+			Text("Member"),
+		),
+	).Class("card")
+}
+`,
+		},
+		{
+			name: "alpine template x-for and x-if",
+			html: `
+<template x-for="item in items" :key="item.id">
+  <li x-text="item.name"></li>
+</template>
+<template x-if="open">
+  <div class="modal"><span>Hi</span></div>
+</template>
+<table>
+  <tbody>
+    <template x-for="row in rows"><tr><td x-text="row.name"></td></tr></template>
+  </tbody>
+</table>
+`,
+			gocode: `package hello
+
+var n = Body(
+	Template(
+		Li().Attr("x-text", "item.name"),
+	).Attr("x-for", "item in items").
+		Attr("x-bind:key", "item.id"),
+	Template(
+		Div(
+			Span("Hi"),
+		).Class("modal"),
+	).Attr("x-if", "open"),
+	Table(
+		Tbody(
+			Template(
+				Tr(
+					Td().Attr("x-text", "row.name"),
+				),
+			).Attr("x-for", "row in rows"),
+		),
+	),
+)
+`,
+		},
+		{
+			name: "constructor argument attributes",
+			html: `
+<div>
+  <img src="/logo.png" alt="Logo" class="logo">
+  <link href="/style.css" rel="stylesheet">
+  <video controls><source src="/movie.mp4" type="video/mp4"><track src="/subs.vtt" kind="subtitles"></video>
+  <object data="/movie.swf" type="application/x-shockwave-flash"><param name="quality" value="high"></object>
+  <time datetime="2021-09-17">Friday</time>
+</div>
+`,
+			gocode: `package hello
+
+var n = Body(
+	Div(
+		Img("/logo.png").Alt("Logo").
+			Class("logo"),
+		Link("/style.css").Rel("stylesheet"),
+		Video(
+			Source("/movie.mp4").Type("video/mp4"),
+			Track("/subs.vtt").Attr("kind", "subtitles"),
+		).Attr("controls", ""),
+		Object("/movie.swf").Type("application/x-shockwave-flash").
+			Children(
+				Param("quality").Value("high"),
+			),
+		Time("2021-09-17").
+			Children(
+				Text("Friday"),
+			),
+	),
+)
+`,
+		},
+		{
+			name: "alpine shorthand attributes",
+			html: `
+<button @click="open = !open" :class="{'active': open}">Menu</button>
+`,
+			gocode: `package hello
+
+var n = Body(
+	Button("Menu").Attr("x-on:click", "open = !open").
+		Attr("x-bind:class", |backquote|{"active": open}|backquote|),
+)
 `,
 		},
 	}
@@ -0,0 +1,437 @@
+package parse
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// GenerateHTMLGoFromPlaceholders converts HTML containing Handlebars/Mustache
+// ({{name}}, {{#each items}}, {{#if ok}}) or Jinja ({{ name }},
+// {% for item in items %}, {% if ok %}) placeholders into a function whose
+// parameters are the placeholders, named cfg.Name or Component. Parameters are strings, bools for
+// conditions and []string for loops unless cfg overrides their type. Uses the
+// type of a parameter can't serve, like a field of a string item, are errors.
+func GenerateHTMLGoFromPlaceholders(pkg string, childrenMode bool, cfg *Config, htmlCode io.Reader) string {
+	return mustGenerate(newConverter(pkg, childrenMode, cfg).ConvertPlaceholders(htmlCode))
+}
+
+// ConvertPlaceholders is GenerateHTMLGoFromPlaceholders with the options of
+// c.
+func (c *Converter) ConvertPlaceholders(htmlCode io.Reader) (r string, err error) {
+	if err = c.validate(); err != nil {
+		return
+	}
+	src, err := ioutil.ReadAll(htmlCode)
+	if err != nil {
+		return
+	}
+	cfg := c.opts.Config
+
+	pc := &placeholderConverter{
+		cfg: &cfg,
+		tc: &templateConverter{
+			html: &strings.Builder{},
+			used: map[string]bool{},
+		},
+	}
+	err = pc.convert(string(src))
+	if err != nil {
+		return
+	}
+	if pc.tc.err != nil {
+		err = pc.tc.err
+		return
+	}
+
+	body, err := parseBody(strings.NewReader(pc.tc.html.String()))
+	if err != nil {
+		return
+	}
+
+	fc := convert(body, c.methodNames, pc.tc.exprs, cfg.Whitespace)
+	fc.Params = append(pc.params, fc.Params...)
+	if len(cfg.Name) == 0 {
+		cfg.Name = "Component"
+	}
+	return generate(fc, c.methodNames, c.opts.Pkg, c.opts.ChildrenMode, &cfg, ShapeFunc)
+}
+
+var placeholderTag = regexp.MustCompile(`(?s)\{\{\{?(.*?)\}?\}\}|\{%-?(.*?)-?%\}|\{#.*?#\}`)
+
+type placeholderFrame struct {
+	kind string
+	// source of the tag opening the frame
+	tag string
+	// loop variable of for/each frames, and its name in the template
+	item     string
+	itemName string
+	// parameter looped over, if the collection is one
+	coll string
+	// set when the block is inside a tag, its content is collected as a Go
+	// string expression instead of HTML
+	inTag     bool
+	cond      string
+	then      []string
+	otherwise []string
+	inElse    bool
+	// number of nested ifs closed by the same endif, for elif
+	elifs int
+}
+
+type placeholderConverter struct {
+	cfg *Config
+	tc  *templateConverter
+	// tag is the source of the current placeholder tag
+	tag    string
+	params []*funcParam
+	frames []*placeholderFrame
+}
+
+func (pc *placeholderConverter) convert(src string) (err error) {
+	last := 0
+	for _, loc := range placeholderTag.FindAllStringSubmatchIndex(src, -1) {
+		pc.text(src[last:loc[0]])
+		last = loc[1]
+		pc.tag = src[loc[0]:loc[1]]
+		switch {
+		case loc[2] >= 0:
+			err = pc.mustache(strings.TrimSpace(src[loc[2]:loc[3]]))
+		case loc[4] >= 0:
+			err = pc.jinja(strings.TrimSpace(src[loc[4]:loc[5]]))
+		}
+		if err != nil {
+			return
+		}
+	}
+	pc.text(src[last:])
+	if len(pc.frames) > 0 {
+		return fmt.Errorf("unclosed %s block", pc.frames[len(pc.frames)-1].kind)
+	}
+	return
+}
+
+func (pc *placeholderConverter) mustache(tag string) error {
+	fields := strings.Fields(tag)
+	if len(fields) == 0 {
+		return nil
+	}
+	switch {
+	case strings.HasPrefix(tag, "!"):
+		return nil
+	case fields[0] == "#each" && len(fields) > 1:
+		pc.openLoop("each", "", fields[1])
+		return nil
+	case (fields[0] == "#if" || fields[0] == "#unless") && len(fields) > 1:
+		pc.openIf(fields[0][1:], pc.cond(fields[1], fields[0] == "#unless"))
+		return nil
+	case fields[0] == "else":
+		return pc.otherwise()
+	case strings.HasPrefix(fields[0], "/"):
+		return pc.close(fields[0][1:])
+	}
+	pc.expr(pc.value(strings.TrimPrefix(fields[0], "&")), pc.tag)
+	return nil
+}
+
+func (pc *placeholderConverter) jinja(tag string) error {
+	fields := strings.Fields(tag)
+	if len(fields) == 0 {
+		return nil
+	}
+	switch fields[0] {
+	case "for":
+		if len(fields) < 4 || fields[2] != "in" {
+			return fmt.Errorf("unsupported for: %s", tag)
+		}
+		pc.openLoop("for", fields[1], fields[3])
+	case "if":
+		pc.openIf("if", pc.jinjaCond(fields[1:]))
+	case "elif":
+		if err := pc.otherwise(); err != nil {
+			return err
+		}
+		f := pc.frames[len(pc.frames)-1]
+		pc.openIf("if", pc.jinjaCond(fields[1:]))
+		pc.frames[len(pc.frames)-1].elifs = f.elifs + 1
+	case "else":
+		return pc.otherwise()
+	case "endfor":
+		return pc.close("for")
+	case "endif":
+		for i := pc.frames[len(pc.frames)-1].elifs; i > 0; i-- {
+			if err := pc.close("if"); err != nil {
+				return err
+			}
+		}
+		return pc.close("if")
+	default:
+		return fmt.Errorf("unsupported tag: {%% %s %%}", tag)
+	}
+	return nil
+}
+
+func (pc *placeholderConverter) jinjaCond(fields []string) string {
+	if len(fields) == 2 && fields[0] == "not" {
+		return pc.cond(fields[1], true)
+	}
+	if len(fields) == 0 {
+		return "false"
+	}
+	return pc.cond(fields[0], false)
+}
+
+func (pc *placeholderConverter) inTag() bool {
+	if len(pc.frames) > 0 && pc.frames[len(pc.frames)-1].inTag {
+		return true
+	}
+	return pc.tc.inTag
+}
+
+func (pc *placeholderConverter) text(s string) {
+	if len(s) == 0 {
+		return
+	}
+	if f := pc.tagFrame(); f != nil {
+		f.add(strconv.Quote(s))
+		return
+	}
+	pc.tc.write(s)
+}
+
+// expr writes the Go expression of src, the source of the placeholder.
+func (pc *placeholderConverter) expr(expr string, src string) {
+	if f := pc.tagFrame(); f != nil {
+		f.add(expr)
+		return
+	}
+	if pc.tc.failInName(src) {
+		return
+	}
+	pc.tc.writeExpr(expr)
+}
+
+func (pc *placeholderConverter) tagFrame() *placeholderFrame {
+	if len(pc.frames) == 0 || !pc.frames[len(pc.frames)-1].inTag {
+		return nil
+	}
+	return pc.frames[len(pc.frames)-1]
+}
+
+func (f *placeholderFrame) add(part string) {
+	if f.inElse {
+		f.otherwise = append(f.otherwise, part)
+		return
+	}
+	f.then = append(f.then, part)
+}
+
+func (pc *placeholderConverter) openIf(kind string, cond string) {
+	f := &placeholderFrame{kind: kind, tag: pc.tag, cond: cond, inTag: pc.inTag()}
+	if !f.inTag {
+		pc.tc.open(annotationIf, cond)
+	}
+	pc.frames = append(pc.frames, f)
+}
+
+func (pc *placeholderConverter) openLoop(kind string, item string, collection string) {
+	if len(item) == 0 {
+		item = "item"
+	}
+	f := &placeholderFrame{kind: kind, tag: pc.tag, item: goIdent(item), itemName: item}
+	coll := pc.typedValue(collection, "[]string")
+	if typ, ok := pc.paramType(coll); ok {
+		f.coll = coll
+		if !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && !pc.configured(coll) {
+			pc.fail("%s: %s is used as a %s and as a list, set its type in the params of the config", pc.tag, coll, typ)
+		}
+	}
+	pc.tc.open(annotationRange, coll, annotationRangeValue, f.item)
+	pc.frames = append(pc.frames, f)
+}
+
+func (pc *placeholderConverter) otherwise() error {
+	if len(pc.frames) == 0 {
+		return fmt.Errorf("else outside of a block")
+	}
+	f := pc.frames[len(pc.frames)-1]
+	f.inElse = true
+	if !f.inTag {
+		pc.tc.open(annotationElse, "")
+	}
+	return nil
+}
+
+func (pc *placeholderConverter) close(kind string) error {
+	if len(pc.frames) == 0 {
+		return fmt.Errorf("unexpected end of %s", kind)
+	}
+	f := pc.frames[len(pc.frames)-1]
+	pc.frames = pc.frames[:len(pc.frames)-1]
+	if f.kind != kind {
+		return fmt.Errorf("%s closed by %s", f.kind, kind)
+	}
+
+	if f.inTag {
+		pc.expr(fmt.Sprintf("func() string {\nif %s {\nreturn %s\n}\nreturn %s\n}()",
+			f.cond, joinParts(f.then), joinParts(f.otherwise)), f.tag)
+		return nil
+	}
+	if f.inElse {
+		pc.tc.write("</template>")
+	}
+	pc.tc.write("</template>")
+	return nil
+}
+
+// goIdent is name, followed by _ if it is a Go keyword like type.
+func goIdent(name string) string {
+	if token.IsKeyword(name) {
+		return name + "_"
+	}
+	return name
+}
+
+func joinParts(parts []string) string {
+	if len(parts) == 0 {
+		return `""`
+	}
+	return strings.Join(parts, " + ")
+}
+
+// loopItem returns the loop variable a placeholder path refers to.
+func (pc *placeholderConverter) loopItem(name string) (item string, ok bool) {
+	if f := pc.loopFrame(name); f != nil {
+		return f.item, true
+	}
+	return
+}
+
+func (pc *placeholderConverter) loopFrame(name string) *placeholderFrame {
+	for i := len(pc.frames) - 1; i >= 0; i-- {
+		f := pc.frames[i]
+		if len(f.item) == 0 {
+			continue
+		}
+		if name == f.itemName || name == "this" {
+			return f
+		}
+	}
+	return nil
+}
+
+// value converts a placeholder path like user.name into a Go expression,
+// either a field of a loop variable or a parameter, formatted if it isn't
+// a string.
+func (pc *placeholderConverter) value(path string) string {
+	path = strings.TrimSpace(strings.Split(path, "|")[0])
+	segs := strings.Split(path, ".")
+	if f := pc.loopFrame(segs[0]); f != nil {
+		// the items of the default []string have no fields
+		if len(segs) > 1 && len(f.coll) > 0 && !pc.configured(f.coll) {
+			pc.fail("%s: the items of %s have no field %s, set the type of %s in the params of the config",
+				pc.tag, f.coll, segs[1], f.coll)
+		}
+		item := f.item
+		for _, s := range segs[1:] {
+			item += "." + strcase.ToCamel(s)
+		}
+		return item
+	}
+	name := pc.param(path, "string")
+	if typ, _ := pc.paramType(name); typ != "string" {
+		return fmt.Sprintf("fmt.Sprint(%s)", name)
+	}
+	return name
+}
+
+// cond converts a placeholder path into a boolean expression, testing
+// strings and slices for emptiness like the template languages do.
+func (pc *placeholderConverter) cond(path string, negate bool) string {
+	v := pc.typedValue(path, "bool")
+	typ, ok := pc.paramType(v)
+	if !ok {
+		typ = "bool"
+	}
+	switch {
+	case strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "func(") || typ == "error":
+		if negate {
+			return v + " == nil"
+		}
+		return v + " != nil"
+	case numericTypes[typ]:
+		if negate {
+			return v + " == 0"
+		}
+		return v + " != 0"
+	case typ == "string":
+		if negate {
+			return v + ` == ""`
+		}
+		return v + ` != ""`
+	case strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map["):
+		if negate {
+			return "len(" + v + ") == 0"
+		}
+		return "len(" + v + ") > 0"
+	case negate:
+		return "!" + v
+	}
+	return v
+}
+
+func (pc *placeholderConverter) typedValue(path string, typ string) string {
+	if _, ok := pc.loopItem(strings.Split(path, ".")[0]); ok {
+		return pc.value(path)
+	}
+	return pc.param(path, typ)
+}
+
+var numericTypes = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true,
+}
+
+// paramType returns the type of the parameter name, if there is one.
+func (pc *placeholderConverter) paramType(name string) (typ string, ok bool) {
+	for _, p := range pc.params {
+		if p.Name == name {
+			return p.goType(), true
+		}
+	}
+	return
+}
+
+// configured reports whether the config sets the type of the parameter.
+func (pc *placeholderConverter) configured(name string) bool {
+	_, ok := pc.cfg.Params[name]
+	return ok
+}
+
+// fail sets the error of the conversion, unless there is one already.
+func (pc *placeholderConverter) fail(format string, a ...interface{}) {
+	if pc.tc.err == nil {
+		pc.tc.err = fmt.Errorf(format, a...)
+	}
+}
+
+func (pc *placeholderConverter) param(path string, typ string) string {
+	name := goIdent(strcase.ToLowerCamel(strings.ReplaceAll(path, ".", "_")))
+	for _, p := range pc.params {
+		if p.Name == name {
+			return name
+		}
+	}
+	if t, ok := pc.cfg.Params[name]; ok {
+		typ = t
+	}
+	pc.params = append(pc.params, &funcParam{Name: name, Type: typ})
+	return name
+}
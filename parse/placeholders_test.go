@@ -0,0 +1,175 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestGenerateHTMLGoFromPlaceholders(t *testing.T) {
+	var cases = []struct {
+		name   string
+		config *parse.Config
+		html   string
+		gocode string
+	}{
+		{
+			name: "jinja",
+			html: `
+<div class="card {% if active %}card-active{% endif %}">
+  <h2>{{ title }}</h2>
+  <a href="{{ user.url }}">{{ user.name|upper }}</a>
+  {% if show_tags %}
+  <ul>{% for tag in tags %}<li>{{ tag }}</li>{% endfor %}</ul>
+  {% else %}<p>No tags</p>{% endif %}
+</div>
+`,
+			gocode: `package hello
+
+func Component(active bool, title string, userUrl string, userName string, showTags bool, tags []string) HTMLComponent {
+	return Body(
+		Div(
+			H2(title),
+			A(
+				Text(userName),
+			).Href(userUrl),
+			If(showTags,
+				Ul(
+					func() (r HTMLComponents) {
+						for _, tag := range tags {
+							r = append(r,
+								Li(
+									Text(tag),
+								),
+							)
+						}
+						return
+					}(),
+				),
+			).
+				Else(
+					P(
+						Text("No tags"),
+					),
+				),
+		).Class("card " + func() string {
+			if active {
+				return "card-active"
+			}
+			return ""
+		}()),
+	)
+}
+`,
+		},
+		{
+			name: "handlebars with config",
+			config: &parse.Config{
+				Name:   "LinkList",
+				Params: map[string]string{"links": "[]Link"},
+			},
+			html: `
+<nav>
+  {{#each links}}<a href="{{this.href}}">{{this.label}}</a>{{/each}}
+  {{#unless links}}<span>{{empty}}</span>{{/unless}}
+</nav>
+`,
+			gocode: `package hello
+
+func LinkList(links []Link, empty string) HTMLComponent {
+	return Body(
+		Nav(
+			func() (r HTMLComponents) {
+				for _, item := range links {
+					r = append(r,
+						A(
+							Text(item.Label),
+						).Href(item.Href),
+					)
+				}
+				return
+			}(),
+			If(len(links) == 0,
+				Span(empty),
+			),
+		),
+	)
+}
+`,
+		},
+		{
+			name: "keywords",
+			html: `<p title="{{type}}">{{type}}</p>{% for range in ranges %}<i>{{ range }}</i>{% endfor %}`,
+			gocode: `package hello
+
+func Component(type_ string, ranges []string) HTMLComponent {
+	return Body(
+		P(
+			Text(type_),
+		).Title(type_),
+		func() (r HTMLComponents) {
+			for _, range_ := range ranges {
+				r = append(r,
+					I(range_),
+				)
+			}
+			return
+		}(),
+	)
+}
+`,
+		},
+		{
+			name:   "parameters used with several types",
+			html:   `{{#if ok}}<b>Yes</b>{{/if}}<p>{{ok}}</p>{{#if count}}<i>{{count}}</i>{{/if}}`,
+			config: &parse.Config{Params: map[string]string{"count": "int"}},
+			gocode: `package hello
+
+func Component(ok bool, count int) HTMLComponent {
+	return Body(
+		If(ok,
+			B("Yes"),
+		),
+		P(
+			Text(fmt.Sprint(ok)),
+		),
+		If(count != 0,
+			I(fmt.Sprint(count)),
+		),
+	)
+}
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gocode := parse.GenerateHTMLGoFromPlaceholders("", false, c.config, strings.NewReader(c.html))
+			diff := testingutils.PrettyJsonDiff(c.gocode, gocode)
+
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestConvertPlaceholdersErrors(t *testing.T) {
+	cases := map[string]string{
+		"block in attribute name": `<a {{#if x}}hidden{{/if}}>y</a>`,
+		"attribute name":          `<a {{attrs}}>y</a>`,
+		"jinja attribute name":    `<a href="/" {% if x %}hidden{% endif %}>y</a>`,
+		"field of a string item":  `{% for u in users %}<p>{{ u.name }}</p>{% endfor %}`,
+		"loop over a string":      `<p>{{name}}</p>{{#each name}}<i>{{this}}</i>{{/each}}`,
+	}
+	for name, html := range cases {
+		t.Run(name, func(t *testing.T) {
+			code, err := parse.NewConverter(parse.Options{}).ConvertPlaceholders(strings.NewReader(html))
+			if err == nil {
+				t.Errorf("no error, generated %s", code)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+//go:embed playground
+var playgroundFiles embed.FS
+
+// playgroundRequest is the HTML and the options the playground converts it
+// with.
+type playgroundRequest struct {
+	HTML         string `json:"html"`
+	Pkg          string `json:"pkg"`
+	ChildrenMode bool   `json:"childrenMode"`
+	Target       string `json:"target"`
+	Shape        string `json:"shape"`
+	Whitespace   string `json:"whitespace"`
+}
+
+type playgroundResponse struct {
+	Code        string       `json:"code"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Warnings    []string     `json:"warnings"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// Playground returns the handler of the web page converting the HTML pasted
+// into it while typing. It serves the page at / and converts the JSON
+// requests posted to /convert with a Converter.
+func Playground() http.Handler {
+	static, err := fs.Sub(playgroundFiles, "playground")
+	if err != nil {
+		panic(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(static)))
+	mux.HandleFunc("/targets", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, Targets())
+	})
+	mux.HandleFunc("/convert", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req playgroundRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, playgroundConvert(req))
+	})
+	return mux
+}
+
+// playgroundConvert converts the HTML of req, returning the errors in the
+// response.
+func playgroundConvert(req playgroundRequest) (resp playgroundResponse) {
+	resp.Diagnostics = []Diagnostic{}
+	resp.Warnings = []string{}
+
+	ds, err := Lint(strings.NewReader(req.HTML))
+	if err != nil {
+		resp.Error = err.Error()
+		return
+	}
+	resp.Diagnostics = append(resp.Diagnostics, ds...)
+
+	warnings := bytes.NewBuffer(nil)
+	c := NewConverter(Options{
+		Pkg:          req.Pkg,
+		ChildrenMode: req.ChildrenMode,
+		Config: Config{
+			Target:     req.Target,
+			Shape:      req.Shape,
+			Whitespace: req.Whitespace,
+			Warnings:   warnings,
+		},
+	})
+	code, err := c.Convert(strings.NewReader(req.HTML))
+	s := bufio.NewScanner(warnings)
+	for s.Scan() {
+		resp.Warnings = append(resp.Warnings, s.Text())
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		return
+	}
+	resp.Code = code
+	return
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,83 @@
+"use strict";
+
+const fields = ["html", "pkg", "childrenMode", "target", "shape", "whitespace"];
+const $ = (id) => document.getElementById(id);
+
+let timer;
+let latest = 0;
+
+// convert posts the HTML and the options, and shows the code of the latest
+// request only, so that slow answers don't overwrite newer ones.
+async function convert() {
+  const id = ++latest;
+  const req = {
+    html: $("html").value,
+    pkg: $("pkg").value,
+    childrenMode: $("childrenMode").checked,
+    target: $("target").value,
+    shape: $("shape").value,
+    whitespace: $("whitespace").value,
+  };
+  localStorage.setItem("html2go", JSON.stringify(req));
+
+  let resp;
+  try {
+    const r = await fetch("convert", { method: "POST", body: JSON.stringify(req) });
+    resp = await r.json();
+  } catch (e) {
+    resp = { error: String(e), diagnostics: [], warnings: [] };
+  }
+  if (id !== latest) {
+    return;
+  }
+
+  $("code").textContent = resp.error || resp.code;
+  $("code").classList.toggle("error", !!resp.error);
+  const messages = $("messages");
+  messages.textContent = "";
+  for (const d of resp.diagnostics) {
+    const li = document.createElement("li");
+    li.textContent = `${d.line}:${d.column}: ${d.message}`;
+    messages.appendChild(li);
+  }
+  for (const w of resp.warnings) {
+    const li = document.createElement("li");
+    li.textContent = w;
+    messages.appendChild(li);
+  }
+}
+
+function schedule() {
+  clearTimeout(timer);
+  timer = setTimeout(convert, 150);
+}
+
+async function init() {
+  const targets = await (await fetch("targets")).json();
+  for (const t of targets) {
+    const o = document.createElement("option");
+    o.textContent = t;
+    $("target").appendChild(o);
+  }
+  $("target").value = "htmlgo";
+
+  const saved = JSON.parse(localStorage.getItem("html2go") || "{}");
+  for (const f of fields) {
+    if (!(f in saved)) {
+      continue;
+    }
+    if ($(f).type === "checkbox") {
+      $(f).checked = saved[f];
+    } else {
+      $(f).value = saved[f];
+    }
+  }
+
+  for (const f of fields) {
+    $(f).addEventListener("input", schedule);
+    $(f).addEventListener("change", schedule);
+  }
+  convert();
+}
+
+init();
@@ -0,0 +1,80 @@
+package parse_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestPlayground(t *testing.T) {
+	srv := httptest.NewServer(parse.Playground())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(page), `<textarea id="html"`) {
+		t.Fatalf("page: %d %s", resp.StatusCode, page)
+	}
+
+	type response struct {
+		Code        string
+		Diagnostics []parse.Diagnostic
+		Warnings    []string
+		Error       string
+	}
+	convert := func(req string) (r response) {
+		resp, err := http.Post(srv.URL+"/convert", "application/json", strings.NewReader(req))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status %d", resp.StatusCode)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	r := convert(`{"html": "<p><b>Hi</p>", "pkg": "h", "shape": "expr"}`)
+	expected := response{
+		Code: `h.Body(
+	h.P(
+		h.B("Hi"),
+	),
+)
+`,
+		Diagnostics: []parse.Diagnostic{{Line: 1, Column: 9, Message: "</p> closes the unclosed <b> from 1:4"}},
+		Warnings:    []string{},
+	}
+	diff := testingutils.PrettyJsonDiff(expected, r)
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+
+	r = convert(`{"html": "<p>Hi</p>", "target": "jsx"}`)
+	if !strings.Contains(r.Error, `unknown target "jsx"`) || len(r.Code) > 0 {
+		t.Errorf("unknown target: %#v", r)
+	}
+
+	resp, err = http.Get(srv.URL + "/convert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /convert: %d", resp.StatusCode)
+	}
+}
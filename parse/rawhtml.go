@@ -0,0 +1,175 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// RewriteRawHTML replaces the htmlgo RawHTML calls of the Go file src whose
+// argument is a constant string by the builder code of the HTML, with the
+// package prefix of the htmlgo import of the file. It returns src as is if
+// there are none. Calls whose HTML the parser has to repair or that can't be
+// converted are kept and reported to Warnings, named after filename.
+func (c *Converter) RewriteRawHTML(filename string, src []byte) (r []byte, err error) {
+	if len(c.opts.Target) > 0 && c.opts.Target != TargetHTMLGo {
+		err = fmt.Errorf("RawHTML calls can only be rewritten to %s, not %s", TargetHTMLGo, c.opts.Target)
+		return
+	}
+	fset, f, err := parseGoFile(src)
+	if err != nil {
+		return
+	}
+	pkg, ok := htmlgoImport(f)
+	if !ok {
+		return src, nil
+	}
+
+	c2 := *c
+	c2.opts.Pkg = pkg
+	if pkg == "." {
+		c2.opts.Pkg = ""
+	}
+	c2.opts.Shape = ShapeVar
+	// the HTML of RawHTML has no body around it
+	c2.opts.DropImplicit = true
+	// the builder code must render the same page
+	if len(c2.opts.Whitespace) == 0 {
+		c2.opts.Whitespace = WhitespaceCollapse
+	}
+	c2.opts.exactStrings = true
+
+	var edits []edit
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 || !isRawHTMLFunc(call.Fun, pkg) {
+			return true
+		}
+		htmlCode, ok := constString(call.Args[0])
+		if !ok || len(strings.TrimSpace(htmlCode)) == 0 {
+			return true
+		}
+		es, cerr := c2.rawHTMLEdits(fset, f, call, htmlCode)
+		if cerr != nil {
+			c.warn("%s:%d: RawHTML kept: %v\n", filename, fset.Position(call.Pos()).Line, cerr)
+			return false
+		}
+		edits = append(edits, es...)
+		return false
+	})
+	if len(edits) == 0 {
+		return src, nil
+	}
+	return applyEdits(src, edits)
+}
+
+// rawHTMLEdits returns the edits replacing call by the builder code of
+// htmlCode, and adding the functions extracted from it.
+func (c *Converter) rawHTMLEdits(fset *token.FileSet, f *ast.File, call *ast.CallExpr, htmlCode string) (r []edit, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	ds, err := Lint(strings.NewReader(htmlCode))
+	if err != nil {
+		return
+	}
+	if len(ds) > 0 {
+		err = fmt.Errorf("HTML %s", ds[0])
+		return
+	}
+
+	gfset, gf, err := c.ConvertAST(strings.NewReader(htmlCode))
+	if err != nil {
+		return
+	}
+	value := gf.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0]
+	if comps, ok := value.(*ast.CallExpr); ok && len(comps.Args) == 1 && funcName(comps.Fun) == "Components" {
+		value = comps.Args[0]
+	}
+	code, err := printNode(gfset, gf, value)
+	if err != nil {
+		return
+	}
+	r = append(r, newEdit(fset, call.Pos(), call.End(), code))
+
+	gf.Decls = gf.Decls[1:]
+	funcEdits, err := componentFuncEdits(fset, f, gfset, gf)
+	r = append(r, funcEdits...)
+	return
+}
+
+func (c *Converter) warn(format string, a ...interface{}) {
+	if c.opts.Warnings != nil {
+		_, _ = fmt.Fprintf(c.opts.Warnings, format, a...)
+	}
+}
+
+// htmlgoImport returns the name htmlgo is imported as by f, "." if it's dot
+// imported.
+func htmlgoImport(f *ast.File) (name string, ok bool) {
+	for _, imp := range f.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || path != htmlgoPath {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name, imp.Name.Name != "_"
+		}
+		return "htmlgo", true
+	}
+	return
+}
+
+func isRawHTMLFunc(fun ast.Expr, pkg string) bool {
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		return pkg == "." && fun.Name == "RawHTML"
+	case *ast.SelectorExpr:
+		x, ok := fun.X.(*ast.Ident)
+		return ok && x.Name == pkg && fun.Sel.Name == "RawHTML"
+	}
+	return false
+}
+
+// constString returns the value of the constant string expression x: string
+// literals, constants of the file declared with them, and their sums.
+func constString(x ast.Expr) (s string, ok bool) {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		if x.Kind != token.STRING {
+			return
+		}
+		s, err := strconv.Unquote(x.Value)
+		return s, err == nil
+	case *ast.ParenExpr:
+		return constString(x.X)
+	case *ast.BinaryExpr:
+		if x.Op != token.ADD {
+			return
+		}
+		l, ok := constString(x.X)
+		if !ok {
+			return "", false
+		}
+		r, ok := constString(x.Y)
+		return l + r, ok
+	case *ast.Ident:
+		if x.Obj == nil || x.Obj.Kind != ast.Con {
+			return
+		}
+		spec, isSpec := x.Obj.Decl.(*ast.ValueSpec)
+		if !isSpec {
+			return
+		}
+		for i, name := range spec.Names {
+			if name.Name == x.Name && i < len(spec.Values) {
+				return constString(spec.Values[i])
+			}
+		}
+	}
+	return
+}
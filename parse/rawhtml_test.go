@@ -0,0 +1,183 @@
+package parse_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+	"golang.org/x/net/html"
+)
+
+func TestRewriteRawHTML(t *testing.T) {
+	src := "package page\n\n" +
+		"import (\n\t\"fmt\"\n\n\th \"github.com/theplant/htmlgo\"\n)\n\n" +
+		"const footer = `<footer class=\"f\">` + \"(c)\" + `</footer>`\n\n" +
+		"func Page(name string) h.HTMLComponent {\n" +
+		"\t// the header\n" +
+		"\treturn h.Div(\n" +
+		"\t\th.RawHTML(`<header><h1 class=\"title\">Hi</h1></header>`),\n" +
+		"\t\th.RawHTML(\"<p>\" + name + \"</p>\"),\n" +
+		"\t\th.RawHTML(`<li>a</li><li>b</li>`),\n" +
+		"\t\th.RawHTML(`<p><b>open</p>`),\n" +
+		"\t\th.RawHTML(footer),\n" +
+		"\t\th.Text(fmt.Sprint(1)),\n" +
+		"\t)\n" +
+		"}\n"
+
+	warnings := bytes.NewBuffer(nil)
+	c := parse.NewConverter(parse.Options{Config: parse.Config{Warnings: warnings}})
+	r, err := c.RewriteRawHTML("page.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `package page
+
+import (
+	"fmt"
+
+	h "github.com/theplant/htmlgo"
+)
+
+const footer = ` + "`" + `<footer class="f">` + "` + \"(c)\" + `" + `</footer>` + "`" + `
+
+func Page(name string) h.HTMLComponent {
+	// the header
+	return h.Div(
+		h.Header(
+			h.H1("Hi").Class("title"),
+		),
+		h.RawHTML("<p>"+name+"</p>"),
+		h.Components(
+			h.Li(
+				h.Text("a"),
+			),
+			h.Li(
+				h.Text("b"),
+			),
+		),
+		h.RawHTML(` + "`<p><b>open</p>`" + `),
+		h.Footer(
+			h.Text("(c)"),
+		).Class("f"),
+		h.Text(fmt.Sprint(1)),
+	)
+}
+`
+	diff := testingutils.PrettyJsonDiff(expected, string(r))
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+	diff = testingutils.PrettyJsonDiff("page.go:17: RawHTML kept: HTML 1:11: </p> closes the unclosed <b> from 1:4\n", warnings.String())
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+
+	src = "package page\n\nvar n = RawHTML(`<br>`)\n"
+	r, err = c.RewriteRawHTML("page.go", []byte(src))
+	if err != nil || string(r) != src {
+		t.Errorf("file without htmlgo import changed: %s %v", r, err)
+	}
+}
+
+// TestRewriteRawHTMLRender compiles a page before and after the rewrite and
+// compares the HTML the two render.
+func TestRewriteRawHTMLRender(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+	src := "package main\n\n" +
+		"import h \"github.com/theplant/htmlgo\"\n\n" +
+		"func Page() h.HTMLComponent {\n" +
+		"\treturn h.Div(\n" +
+		"\t\th.RawHTML(`<p>Hello <b>World</b>, <a title=\"Don't\" href=\"/x?a=1&amp;b=2\">go</a></p>`),\n" +
+		"\t\th.RawHTML(\"<pre>  if ok {\\n    go()\\n  }</pre>\"),\n" +
+		"\t\th.RawHTML(`<details open><summary>Video</summary><video controls src=\"/v.mp4\"></video><img alt=\"\" src=\"/a.png\"></details>`),\n" +
+		"\t)\n" +
+		"}\n"
+	r, err := parse.NewConverter(parse.Options{}).RewriteRawHTML("page.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(r, []byte("RawHTML")) {
+		t.Fatalf("RawHTML kept:\n%s", r)
+	}
+	rewritten := strings.Replace(string(r), "func Page(", "func NewPage(", 1)
+
+	// inside the module to build with its htmlgo
+	dir, err := os.MkdirTemp(".", "_render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"old.go": src,
+		"new.go": rewritten,
+		"main.go": "package main\n\n" +
+			"import (\n\t\"context\"\n\t\"fmt\"\n\n\th \"github.com/theplant/htmlgo\"\n)\n\n" +
+			"func main() {\n" +
+			"\tfmt.Print(h.MustString(Page(), context.TODO()), \"\\x00\", h.MustString(NewPage(), context.TODO()))\n" +
+			"}\n",
+	}
+	for name, code := range files {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(code), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := exec.Command("go", "run", "./"+dir).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s\n%s", err, out, rewritten)
+	}
+	renders := strings.Split(string(out), "\x00")
+	if len(renders) != 2 {
+		t.Fatalf("output: %q", out)
+	}
+	diff := testingutils.PrettyJsonDiff(renderedDOM(t, renders[0]), renderedDOM(t, renders[1]))
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+}
+
+// renderedDOM describes the document of htmlCode as a browser shows it:
+// white space between elements doesn't matter, but it does in <pre>.
+func renderedDOM(t *testing.T, htmlCode string) []string {
+	doc, err := html.Parse(strings.NewReader(htmlCode))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r []string
+	var walk func(n *html.Node, pre bool)
+	walk = func(n *html.Node, pre bool) {
+		switch n.Type {
+		case html.ElementNode:
+			// the order of the attributes doesn't change the page
+			var attrs []string
+			for _, a := range n.Attr {
+				attrs = append(attrs, fmt.Sprintf(" %s=%q", a.Key, a.Val))
+			}
+			sort.Strings(attrs)
+			r = append(r, "<"+n.Data+strings.Join(attrs, "")+">")
+			pre = pre || n.Data == "pre"
+		case html.TextNode:
+			text := n.Data
+			if !pre {
+				text = strings.Join(strings.Fields(text), " ")
+			}
+			if len(text) > 0 {
+				r = append(r, text)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, pre)
+		}
+	}
+	walk(doc, false)
+	return r
+}
@@ -0,0 +1,187 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"strings"
+	"unicode"
+)
+
+// Output shapes of the generated code.
+const (
+	// ShapeVar assigns the component to a package level var.
+	ShapeVar = "var"
+	// ShapeFunc returns the component from a function.
+	ShapeFunc = "func"
+	// ShapeMethod returns the component from a method of Config.Receiver.
+	ShapeMethod = "method"
+	// ShapeExpr prints only the expression, without package clause.
+	ShapeExpr = "expr"
+	// ShapeChildren prints only the comma separated children of the body,
+	// indented by Config.Indent tabs, to paste into an existing call.
+	ShapeChildren = "children"
+)
+
+func generate(fc *funcCall, methodNames []string, pkg string, childrenMode bool, cfg *Config, shape string) (r string, err error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if len(cfg.Shape) > 0 {
+		shape = cfg.Shape
+	}
+
+	if err = applyTagMappings(fc, cfg); err != nil {
+		return
+	}
+
+	if cfg.Target == TargetTempl {
+		return generateTempl(fc, pkg, cfg), nil
+	}
+
+	fset, f, err := generateFile(fc, methodNames, pkg, childrenMode, cfg, shape)
+	if err != nil {
+		return
+	}
+	switch shape {
+	case ShapeExpr:
+		return formatExpr(fset, f)
+	case ShapeChildren:
+		return formatChildren(fset, f, cfg.Indent)
+	}
+	return formatCode(fset, f)
+}
+
+// generateFile returns the file of package hello declaring the component in
+// the shape, followed by the extracted component functions.
+func generateFile(fc *funcCall, methodNames []string, pkg string, childrenMode bool, cfg *Config, shape string) (fset *token.FileSet, f *ast.File, err error) {
+	bk := getBackend(cfg.Target)
+	b := newASTBuilder()
+	b.attrOrder = cfg.AttrOrder
+	b.exactStrings = cfg.exactStrings
+	f = &ast.File{Package: b.write("package")}
+	b.write(" ")
+	f.Name = b.ident("hello")
+	b.write("\n")
+
+	root := fc
+	if cfg.component {
+		root = componentRoot(fc)
+	}
+	body := func() ast.Expr {
+		return bk.marshal(b, root, methodNames, pkg, childrenMode)
+	}
+	switch shape {
+	case ShapeVar, ShapeExpr, ShapeChildren:
+		f.Decls = append(f.Decls, b.varDecl(nameOr(cfg.Name, "n"), body))
+	case ShapeFunc:
+		f.Decls = append(f.Decls, b.funcDecl("", nameOr(cfg.Name, "Render"), fc.funcParams(), cfg.Params,
+			bk.componentType(pkg), body))
+	case ShapeMethod:
+		f.Decls = append(f.Decls, b.funcDecl(receiverType(cfg), nameOr(cfg.Name, "Render"), fc.funcParams(), cfg.Params,
+			bk.componentType(pkg), body))
+	default:
+		err = fmt.Errorf("unknown output shape %q", shape)
+		return
+	}
+	f.Decls = append(f.Decls, marshalComponentFuncs(b, fc, bk, methodNames, pkg, childrenMode)...)
+	f.Comments = b.comments
+	return b.fset, f, b.err
+}
+
+// componentRoot is the only child of the body fc, or else the body as a list
+// of components.
+func componentRoot(fc *funcCall) *funcCall {
+	if len(fc.Children) == 1 {
+		return fc.Children[0]
+	}
+	root := *fc
+	root.Implicit = true
+	return &root
+}
+
+func nameOr(name string, def string) string {
+	if len(name) == 0 {
+		return def
+	}
+	return name
+}
+
+// receiverType is the receiver type of the method shape.
+func receiverType(cfg *Config) string {
+	typ := nameOr(cfg.Receiver, "Page")
+	if strings.HasPrefix(typ, "*") || cfg.ValueReceiver {
+		return typ
+	}
+	return "*" + typ
+}
+
+func receiverName(typ string) string {
+	for _, r := range strings.TrimLeft(typ, "*") {
+		return string(unicode.ToLower(r))
+	}
+	return "p"
+}
+
+var printerConfig = &printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+
+func formatCode(fset *token.FileSet, f *ast.File) (r string, err error) {
+	buf := bytes.NewBuffer(nil)
+	err = printerConfig.Fprint(buf, fset, f)
+	r = buf.String()
+	return
+}
+
+// formatExpr prints the value of the first var declaration followed by the
+// other declarations.
+func formatExpr(fset *token.FileSet, f *ast.File) (r string, err error) {
+	buf := bytes.NewBuffer(nil)
+	for i, d := range f.Decls {
+		var node ast.Node = d
+		if i == 0 {
+			node = d.(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0]
+		} else {
+			buf.WriteString("\n\n")
+		}
+		err = printerConfig.Fprint(buf, fset, &printer.CommentedNode{Node: node, Comments: f.Comments})
+		if err != nil {
+			return
+		}
+	}
+	buf.WriteString("\n")
+	r = buf.String()
+	return
+}
+
+// formatChildren prints the arguments of the body call in the first var
+// declaration, each followed by a comma, then the other declarations.
+func formatChildren(fset *token.FileSet, f *ast.File, indent int) (r string, err error) {
+	pc := *printerConfig
+	pc.Indent = indent
+	buf := bytes.NewBuffer(nil)
+	for i, d := range f.Decls {
+		if i > 0 {
+			buf.WriteString("\n")
+			err = pc.Fprint(buf, fset, &printer.CommentedNode{Node: d, Comments: f.Comments})
+			if err != nil {
+				return
+			}
+			buf.WriteString("\n")
+			continue
+		}
+
+		// Body(...) or, in children mode, Body().Children(...)
+		body := d.(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0].(*ast.CallExpr)
+		for _, arg := range body.Args {
+			err = pc.Fprint(buf, fset, &printer.CommentedNode{Node: arg, Comments: f.Comments})
+			if err != nil {
+				return
+			}
+			buf.WriteString(",\n")
+		}
+	}
+	r = buf.String()
+	return
+}
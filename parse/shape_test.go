@@ -0,0 +1,119 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestGenerateHTMLGoWithConfig(t *testing.T) {
+	html := `<nav class="navbar"><span>Hello</span></nav>`
+
+	var cases = []struct {
+		name   string
+		pkg    string
+		config *parse.Config
+		gocode string
+	}{
+		{
+			name:   "var",
+			config: &parse.Config{Shape: parse.ShapeVar, Name: "navbar"},
+			gocode: `package hello
+
+var navbar = Body(
+	Nav(
+		Span("Hello"),
+	).Class("navbar"),
+)
+`,
+		},
+		{
+			name:   "func",
+			pkg:    "htmlgo",
+			config: &parse.Config{Shape: parse.ShapeFunc, Name: "Navbar"},
+			gocode: `package hello
+
+func Navbar() htmlgo.HTMLComponent {
+	return htmlgo.Body(
+		htmlgo.Nav(
+			htmlgo.Span("Hello"),
+		).Class("navbar"),
+	)
+}
+`,
+		},
+		{
+			name:   "method",
+			config: &parse.Config{Shape: parse.ShapeMethod, Receiver: "Layout", Name: "Navbar"},
+			gocode: `package hello
+
+func (l *Layout) Navbar() HTMLComponent {
+	return Body(
+		Nav(
+			Span("Hello"),
+		).Class("navbar"),
+	)
+}
+`,
+		},
+		{
+			name:   "method of a pointer type",
+			config: &parse.Config{Shape: parse.ShapeMethod, Receiver: "*Layout", Name: "Navbar"},
+			gocode: `package hello
+
+func (l *Layout) Navbar() HTMLComponent {
+	return Body(
+		Nav(
+			Span("Hello"),
+		).Class("navbar"),
+	)
+}
+`,
+		},
+		{
+			name:   "method of a value",
+			config: &parse.Config{Shape: parse.ShapeMethod, Receiver: "Layout", ValueReceiver: true, Name: "Navbar"},
+			gocode: `package hello
+
+func (l Layout) Navbar() HTMLComponent {
+	return Body(
+		Nav(
+			Span("Hello"),
+		).Class("navbar"),
+	)
+}
+`,
+		},
+		{
+			name:   "expr",
+			config: &parse.Config{Shape: parse.ShapeExpr},
+			gocode: `Body(
+	Nav(
+		Span("Hello"),
+	).Class("navbar"),
+)
+`,
+		},
+		{
+			name:   "children",
+			config: &parse.Config{Shape: parse.ShapeChildren, Indent: 1},
+			gocode: `	Nav(
+		Span("Hello"),
+	).Class("navbar"),
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gocode := parse.GenerateHTMLGoWithConfig(c.pkg, false, c.config, strings.NewReader(html))
+			diff := testingutils.PrettyJsonDiff(c.gocode, gocode)
+
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
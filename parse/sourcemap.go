@@ -0,0 +1,166 @@
+package parse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// SourcePosition maps a line of the generated code to the position in the
+// HTML of the element or text it was generated from.
+type SourcePosition struct {
+	GoLine int `json:"goLine"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// position is the line and column of a token in the HTML, starting at 1.
+type position struct {
+	line   int
+	column int
+}
+
+func (p *position) advance(raw []byte) {
+	if i := bytes.LastIndexByte(raw, '\n'); i >= 0 {
+		p.line += bytes.Count(raw, []byte("\n"))
+		p.column = utf8.RuneCount(raw[i+1:]) + 1
+		return
+	}
+	p.column += utf8.RuneCount(raw)
+}
+
+// setPositions sets the line and column of the elements and texts of root.
+// The parser doesn't record positions, so the n-th element with a tag gets
+// the position of the n-th start tag with that name, and the same for texts,
+// as long as the parser didn't insert or drop any of them.
+func setPositions(root *funcCall, src []byte) {
+	tokens := map[string][]position{}
+	pos := position{line: 1, column: 1}
+	z := html.NewTokenizer(bytes.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		start := pos
+		pos.advance(raw)
+		tok := z.Token()
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tokens["<"+tok.Data] = append(tokens["<"+tok.Data], start)
+		case html.TextToken:
+			text := textKey(tok.Data)
+			if len(text) == 0 {
+				continue
+			}
+			// the text starts after the leading white space of the token
+			leading := raw[:bytes.IndexFunc(raw, isNotSpace)]
+			start.advance(leading)
+			tokens[text] = append(tokens[text], start)
+		}
+	}
+
+	calls := map[string][]*funcCall{}
+	var each func(fc *funcCall)
+	each = func(fc *funcCall) {
+		if key := positionKey(fc); len(key) > 0 {
+			calls[key] = append(calls[key], fc)
+		}
+		for _, c := range fc.Children {
+			each(c)
+		}
+		for _, c := range fc.Else {
+			each(c)
+		}
+	}
+	each(root)
+
+	for key, fcs := range calls {
+		if len(fcs) != len(tokens[key]) {
+			continue
+		}
+		for i, fc := range fcs {
+			fc.Line, fc.Column = tokens[key][i].line, tokens[key][i].column
+		}
+	}
+}
+
+func positionKey(fc *funcCall) string {
+	switch {
+	case len(fc.Text) > 0 && len(fc.TextExpr) == 0:
+		return textKey(fc.Text)
+	case len(fc.Tag) > 0:
+		return "<" + fc.Tag
+	}
+	return ""
+}
+
+// textKey is a text without the white space the whitespace policy may have
+// trimmed or collapsed, so a text matches its token under every policy.
+func textKey(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// srcComment is the trailing comment with the HTML position of a call.
+func (fc *funcCall) srcComment() string {
+	if fc.Line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("// src:%d:%d", fc.Line, fc.Column)
+}
+
+var srcCommentRe = regexp.MustCompile(` // src:(\d+):(\d+)$`)
+
+// extractSourceMap removes the source comments from the formatted code and
+// returns them as a source map.
+func extractSourceMap(code string) (r string, sm []SourcePosition) {
+	lines := strings.Split(code, "\n")
+	for i, l := range lines {
+		m := srcCommentRe.FindStringSubmatchIndex(l)
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(l[m[2]:m[3]])
+		column, _ := strconv.Atoi(l[m[4]:m[5]])
+		sm = append(sm, SourcePosition{GoLine: i + 1, Line: line, Column: column})
+		lines[i] = strings.TrimRight(l[:m[0]], " \t")
+	}
+	r = strings.Join(lines, "\n")
+	return
+}
+
+// generateWithSourceMap is generate with the source comments or the source
+// map cfg asks for.
+func generateWithSourceMap(fc *funcCall, src []byte, methodNames []string, pkg string, childrenMode bool, cfg *Config, shape string) (r string, err error) {
+	if !cfg.SourceComments && cfg.SourceMap == nil {
+		return generate(fc, methodNames, pkg, childrenMode, cfg, shape)
+	}
+
+	setPositions(fc, src)
+	code, err := generate(fc, methodNames, pkg, childrenMode, cfg, shape)
+	if err != nil || cfg.SourceMap == nil {
+		return code, err
+	}
+
+	stripped, sm := extractSourceMap(code)
+	b, err := json.MarshalIndent(sm, "", "\t")
+	if err != nil {
+		return
+	}
+	_, err = cfg.SourceMap.Write(append(b, '\n'))
+	if err != nil {
+		return
+	}
+	if cfg.SourceComments {
+		return code, nil
+	}
+	return stripped, nil
+}
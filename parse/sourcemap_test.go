@@ -0,0 +1,180 @@
+package parse_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+const sourceMapHTML = `<div class="card">
+  <h1 class="title">Hello</h1>
+  <img src="a.png">
+  <p>Body &amp; text</p>
+</div>
+`
+
+func TestSourceComments(t *testing.T) {
+	cases := []struct {
+		name     string
+		cfg      *parse.Config
+		expected string
+	}{
+		{
+			name: "htmlgo",
+			cfg:  &parse.Config{SourceComments: true},
+			expected: `package hello
+
+var n = Body(
+	Div( // src:1:1
+		H1("Hello").Class("title"), // src:2:3
+		Img("a.png"),               // src:3:3
+		P( // src:4:3
+			Text("Body & text"), // src:4:6
+		),
+	).Class("card"),
+)
+`,
+		},
+		{
+			name: "gomponents",
+			cfg:  &parse.Config{SourceComments: true, Target: parse.TargetGomponents, Shape: parse.ShapeChildren},
+			expected: `Div(Class("card"), // src:1:1
+	H1(Class("title"), g.Text("Hello")), // src:2:3
+	Img(Src("a.png")),                   // src:3:3
+	P(g.Text("Body & text")),            // src:4:3
+),
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gocode := parse.GenerateHTMLGoWithConfig("", false, c.cfg, strings.NewReader(sourceMapHTML))
+			diff := testingutils.PrettyJsonDiff(c.expected, gocode)
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestSourceCommentsEmptyCalls(t *testing.T) {
+	html := `<div class="x"><br><div id="a" class="b"></div></div>`
+	cases := []struct {
+		name         string
+		childrenMode bool
+		expected     string
+	}{
+		{
+			name: "arguments",
+			expected: `package hello
+
+var n = Body(
+	Div( // src:1:1
+		Br(), // src:1:16
+		Div().Id("a").
+			Class("b"), // src:1:20
+	).Class("x"),
+)
+`,
+		},
+		{
+			name:         "children",
+			childrenMode: true,
+			expected: `package hello
+
+var n = Body().
+	Children(
+		Div().Class("x").
+			Children( // src:1:1
+				Br(), // src:1:16
+				Div().Id("a").
+					Class("b"), // src:1:20
+			),
+	)
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := &parse.Config{SourceComments: true}
+			gocode := parse.GenerateHTMLGoWithConfig("", c.childrenMode, cfg, strings.NewReader(html))
+			diff := testingutils.PrettyJsonDiff(c.expected, gocode)
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestSourceCommentsWhitespace(t *testing.T) {
+	html := "<p>\n  Body   and\n  text <b> bold </b></p>"
+	for _, w := range []string{parse.WhitespaceTrim, parse.WhitespaceCollapse, parse.WhitespacePreserve} {
+		t.Run(w, func(t *testing.T) {
+			cfg := &parse.Config{SourceComments: true, Whitespace: w}
+			gocode := parse.GenerateHTMLGoWithConfig("", false, cfg, strings.NewReader(html))
+			for _, src := range []string{"// src:1:1", "// src:2:3", "// src:3:8"} {
+				if !strings.Contains(gocode, src) {
+					t.Errorf("no %s in\n%s", src, gocode)
+				}
+			}
+		})
+	}
+}
+
+func TestSourceMap(t *testing.T) {
+	sm := bytes.NewBuffer(nil)
+	cfg := &parse.Config{SourceMap: sm, Shape: parse.ShapeExpr}
+	gocode := parse.GenerateHTMLGoWithConfig("", false, cfg, strings.NewReader(sourceMapHTML))
+
+	expected := `Body(
+	Div(
+		H1("Hello").Class("title"),
+		Img("a.png"),
+		P(
+			Text("Body & text"),
+		),
+	).Class("card"),
+)
+`
+	diff := testingutils.PrettyJsonDiff(expected, gocode)
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+
+	expectedMap := `[
+	{
+		"goLine": 2,
+		"line": 1,
+		"column": 1
+	},
+	{
+		"goLine": 3,
+		"line": 2,
+		"column": 3
+	},
+	{
+		"goLine": 4,
+		"line": 3,
+		"column": 3
+	},
+	{
+		"goLine": 5,
+		"line": 4,
+		"column": 3
+	},
+	{
+		"goLine": 6,
+		"line": 4,
+		"column": 6
+	}
+]
+`
+	diff = testingutils.PrettyJsonDiff(expectedMap, sm.String())
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+}
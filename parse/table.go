@@ -0,0 +1,191 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// The HTML parser inserts elements the source has no start tag for, like the
+// tbody of a table whose rows are its direct children, and moves content
+// that is not allowed in a table before it (foster parenting). sourceTags
+// records what the source really contains, so the generated code can be made
+// to follow it.
+type sourceTags struct {
+	// body is set when the source has a <body> start tag.
+	body bool
+	// tables in the order of their start tags.
+	tables []*sourceTable
+}
+
+type sourceTable struct {
+	tbody    int
+	colgroup int
+	// inCell is set while the tokens are inside a cell or caption, where
+	// any content is allowed.
+	inCell bool
+	// fostered are the open elements moved before the table, their content
+	// moves with them.
+	fostered []string
+}
+
+// tableContentTags are the start tags a table keeps in place outside of its
+// cells.
+const tableContentTags = "|caption|colgroup|col|tbody|thead|tfoot|tr|td|th|template|script|style|form|"
+
+// scanSource tokenizes src, counting the tbody and colgroup start tags of
+// every table, and writes a warning with the line number to warnings for
+// content the parser fosters out of a table.
+func scanSource(src []byte, warnings io.Writer) (st *sourceTags) {
+	st = &sourceTags{}
+	var open []*sourceTable
+	line := 1
+	z := html.NewTokenizer(bytes.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return
+		}
+		raw := z.Raw()
+		tokenLine := line
+		line += bytes.Count(raw, []byte("\n"))
+		tok := z.Token()
+
+		var t *sourceTable
+		if len(open) > 0 {
+			t = open[len(open)-1]
+		}
+		if t != nil && len(t.fostered) > 0 && tok.Data != "table" {
+			switch tt {
+			case html.StartTagToken:
+				if !strings.Contains(voidElements, "|"+tok.Data+"|") {
+					t.fostered = append(t.fostered, tok.Data)
+				}
+			case html.EndTagToken:
+				if t.fostered[len(t.fostered)-1] == tok.Data {
+					t.fostered = t.fostered[:len(t.fostered)-1]
+				}
+			}
+			continue
+		}
+		foster := func(what string) {
+			if tt == html.StartTagToken && tok.Data != "table" && !strings.Contains(voidElements, "|"+tok.Data+"|") {
+				t.fostered = append(t.fostered, tok.Data)
+			}
+			if warnings != nil {
+				_, _ = fmt.Fprintf(warnings, "warning: line %d: %s inside <table> is moved before the table by the HTML parser\n", tokenLine, what)
+			}
+		}
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch tok.Data {
+			case "body":
+				st.body = true
+			case "table":
+				if t != nil && !t.inCell && len(t.fostered) == 0 {
+					foster("<table>")
+				}
+				t = &sourceTable{}
+				st.tables = append(st.tables, t)
+				open = append(open, t)
+				continue
+			}
+			if t == nil {
+				continue
+			}
+			switch tok.Data {
+			case "tbody":
+				t.tbody++
+				t.inCell = false
+			case "colgroup":
+				t.colgroup++
+			case "thead", "tfoot", "tr":
+				t.inCell = false
+			case "td", "th", "caption":
+				t.inCell = true
+			case "input":
+				if !t.inCell && !isHiddenInput(tok) {
+					foster("<input>")
+				}
+			default:
+				if !t.inCell && !strings.Contains(tableContentTags, "|"+tok.Data+"|") {
+					foster("<" + tok.Data + ">")
+				}
+			}
+		case html.EndTagToken:
+			if t == nil {
+				continue
+			}
+			switch tok.Data {
+			case "table":
+				open = open[:len(open)-1]
+			case "td", "th", "caption", "tr":
+				t.inCell = false
+			}
+		case html.TextToken:
+			text := strings.TrimSpace(tok.Data)
+			if t != nil && !t.inCell && len(text) > 0 {
+				tokenLine += bytes.Count(raw[:bytes.IndexFunc(raw, isNotSpace)], []byte("\n"))
+				foster(fmt.Sprintf("text %q", text))
+			}
+		}
+	}
+}
+
+func isNotSpace(r rune) bool {
+	return !unicode.IsSpace(r)
+}
+
+func isHiddenInput(tok html.Token) bool {
+	for _, a := range tok.Attr {
+		if a.Key == "type" && strings.EqualFold(a.Val, "hidden") {
+			return true
+		}
+	}
+	return false
+}
+
+// dropImplicit removes the elements of root the parser inserted without a
+// start tag in the source: tbody and colgroup elements are replaced by their
+// children, and the body is generated as a list of components.
+func dropImplicit(root *funcCall, st *sourceTags) {
+	if !st.body {
+		root.Implicit = true
+	}
+
+	var tables []*funcCall
+	collectTables(root, &tables)
+	// the tables can't be matched up if the parser created or dropped some
+	if len(tables) != len(st.tables) {
+		return
+	}
+	for i, fc := range tables {
+		var children []*funcCall
+		for _, c := range fc.Children {
+			if (c.Tag == "tbody" && st.tables[i].tbody == 0) ||
+				(c.Tag == "colgroup" && st.tables[i].colgroup == 0) {
+				children = append(children, c.Children...)
+				continue
+			}
+			children = append(children, c)
+		}
+		fc.Children = children
+	}
+}
+
+func collectTables(fc *funcCall, tables *[]*funcCall) {
+	if fc.Tag == "table" {
+		*tables = append(*tables, fc)
+	}
+	for _, c := range fc.Children {
+		collectTables(c, tables)
+	}
+	for _, c := range fc.Else {
+		collectTables(c, tables)
+	}
+}
@@ -0,0 +1,114 @@
+package parse_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestDropImplicit(t *testing.T) {
+	html := `
+<table class="prices">
+  <col span="2">
+  <tr><td>Tea</td><td>2</td></tr>
+</table>
+<table>
+  <tbody class="rows"><tr><td>Coffee</td></tr></tbody>
+</table>
+`
+	cases := []struct {
+		name     string
+		target   string
+		pkg      string
+		expected string
+	}{
+		{
+			name: "htmlgo",
+			expected: `package hello
+
+var n = Components(
+	Table(
+		Col().Attr("span", "2"),
+		Tr(
+			Td(
+				Text("Tea"),
+			),
+			Td(
+				Text("2"),
+			),
+		),
+	).Class("prices"),
+	Table(
+		Tbody(
+			Tr(
+				Td(
+					Text("Coffee"),
+				),
+			),
+		).Class("rows"),
+	),
+)
+`,
+		},
+		{
+			name:   "gomponents",
+			target: parse.TargetGomponents,
+			pkg:    "h",
+			expected: `package hello
+
+var n = g.Group([]g.Node{
+	h.Table(h.Class("prices"),
+		h.Col(g.Attr("span", "2")),
+		h.Tr(
+			h.Td(g.Text("Tea")),
+			h.Td(g.Text("2")),
+		),
+	),
+	h.Table(
+		h.TBody(h.Class("rows"),
+			h.Tr(
+				h.Td(g.Text("Coffee")),
+			),
+		),
+	),
+})
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := &parse.Config{Target: c.target, DropImplicit: true}
+			gocode := parse.GenerateHTMLGoWithConfig(c.pkg, false, cfg, strings.NewReader(html))
+			diff := testingutils.PrettyJsonDiff(c.expected, gocode)
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestFosterParentingWarnings(t *testing.T) {
+	warnings := bytes.NewBuffer(nil)
+	cfg := &parse.Config{Warnings: warnings}
+
+	html := `<table>
+  <tr><td>In <b>cell</b></td></tr>
+  Total
+  <div>Moved <span>too</span></div>
+  <input type="hidden" name="id">
+  <tr><td><table><tr><td>Nested</td></tr></table></td></tr>
+</table>
+`
+	parse.GenerateHTMLGoWithConfig("", false, cfg, strings.NewReader(html))
+
+	expected := `warning: line 3: text "Total" inside <table> is moved before the table by the HTML parser
+warning: line 4: <div> inside <table> is moved before the table by the HTML parser
+`
+	if warnings.String() != expected {
+		t.Errorf("warnings: %q", warnings.String())
+	}
+}
@@ -0,0 +1,210 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// TargetTempl generates a github.com/a-h/templ component file instead of Go
+// code.
+const TargetTempl = "templ"
+
+const voidElements = "|area|base|br|col|embed|hr|img|input|link|meta|param|source|track|wbr|"
+
+// rawTextElements have their content copied as is, templ does not parse
+// expressions in them.
+const rawTextElements = "|script|style|"
+
+// generateTempl writes the children of the body as the templ component
+// cfg.Name, followed by the extracted components.
+func generateTempl(root *funcCall, pkg string, cfg *Config) string {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("package hello\n")
+
+	tw := &templWriter{buf: buf, attrOrder: cfg.AttrOrder}
+	body := *root
+	body.Name = ""
+	tw.component(nameOr(cfg.Name, "Component"), templParamList(root.funcParams(), cfg.Params), &body)
+
+	done := map[string]bool{}
+	var each func(fc *funcCall)
+	each = func(fc *funcCall) {
+		if len(fc.FuncName) > 0 && !done[fc.FuncName] {
+			done[fc.FuncName] = true
+			c := *fc
+			c.FuncName = ""
+			tw.component(fc.FuncName, templParamList(fc.funcParams(), nil), &c)
+		}
+		for _, c := range fc.Children {
+			each(c)
+		}
+		for _, c := range fc.Else {
+			each(c)
+		}
+	}
+	each(root)
+
+	return buf.String()
+}
+
+type templWriter struct {
+	buf       *bytes.Buffer
+	depth     int
+	attrOrder string
+}
+
+// templParamList formats params as the parameter list of a templ component,
+// with types overridden by types.
+func templParamList(params []*funcParam, types map[string]string) string {
+	var r []string
+	for _, p := range params {
+		r = append(r, p.Name+" "+p.typeIn(types))
+	}
+	return strings.Join(r, ", ")
+}
+
+func (tw *templWriter) line(format string, a ...interface{}) {
+	tw.buf.WriteString(strings.Repeat("\t", tw.depth))
+	_, _ = fmt.Fprintf(tw.buf, format, a...)
+	tw.buf.WriteString("\n")
+}
+
+func (tw *templWriter) component(name string, params string, fc *funcCall) {
+	tw.buf.WriteString("\n")
+	tw.line("templ %s(%s) {", name, params)
+	tw.depth++
+	if len(fc.Name) > 0 {
+		tw.node(fc)
+	} else {
+		tw.nodes(fc.Children)
+	}
+	tw.depth--
+	tw.line("}")
+}
+
+func (tw *templWriter) nodes(fcs []*funcCall) {
+	for _, c := range fcs {
+		tw.node(c)
+	}
+}
+
+func (tw *templWriter) node(fc *funcCall) {
+	switch {
+	case len(fc.FuncName) > 0:
+		tw.line("@%s(%s)", fc.FuncName, strings.Join(fc.funcArgs(), ", "))
+	case fc.isText():
+		tw.line("%s", templText(fc))
+	case len(fc.Expr) > 0:
+		tw.line("@%s", fc.Expr)
+	case len(fc.Cond) > 0:
+		tw.line("if %s {", fc.Cond)
+		tw.block(fc.Children)
+		if len(fc.Else) > 0 {
+			tw.line("} else {")
+			tw.block(fc.Else)
+		}
+		tw.line("}")
+	case len(fc.RangeExpr) > 0:
+		key, value := fc.RangeKey, fc.RangeValue
+		if len(key) == 0 {
+			key = "_"
+		}
+		if len(value) == 0 {
+			value = "item"
+		}
+		tw.line("for %s, %s := range %s {", key, value, fc.RangeExpr)
+		tw.block(fc.Children)
+		tw.line("}")
+		if len(fc.Else) > 0 {
+			tw.line("if len(%s) == 0 {", fc.RangeExpr)
+			tw.block(fc.Else)
+			tw.line("}")
+		}
+	default:
+		tw.element(fc)
+	}
+}
+
+func (tw *templWriter) block(fcs []*funcCall) {
+	tw.depth++
+	tw.nodes(fcs)
+	tw.depth--
+}
+
+func (tw *templWriter) element(fc *funcCall) {
+	tag := fc.Tag
+	var attrs strings.Builder
+	for _, att := range fc.orderedAttrs(tw.attrOrder, untyped) {
+		attrs.WriteString(" ")
+		attrs.WriteString(templAttr(fc, att))
+	}
+
+	if strings.Contains(voidElements, "|"+tag+"|") {
+		tw.line("<%s%s/>", tag, attrs.String())
+		return
+	}
+
+	if strings.Contains(rawTextElements, "|"+tag+"|") {
+		var content []string
+		for _, c := range fc.Children {
+			content = append(content, c.Text)
+		}
+		tw.line("<%s%s>%s</%s>", tag, attrs.String(), strings.Join(content, "\n"), tag)
+		return
+	}
+
+	if len(fc.Children) == 0 {
+		tw.line("<%s%s></%s>", tag, attrs.String(), tag)
+		return
+	}
+	if len(fc.Children) == 1 && fc.Children[0].isText() {
+		tw.line("<%s%s>%s</%s>", tag, attrs.String(), templText(fc.Children[0]), tag)
+		return
+	}
+
+	tw.line("<%s%s>", tag, attrs.String())
+	tw.block(fc.Children)
+	tw.line("</%s>", tag)
+}
+
+// templText escapes text for templ, where { starts an expression and lines
+// starting with a Go keyword or @ are statements.
+func templText(fc *funcCall) string {
+	if len(fc.TextExpr) > 0 {
+		return "{ " + fc.TextExpr + " }"
+	}
+	for _, prefix := range []string{"if ", "for ", "switch ", "@"} {
+		if strings.HasPrefix(fc.Text, prefix) {
+			return fmt.Sprintf("{ %#+v }", fc.Text)
+		}
+	}
+
+	var b strings.Builder
+	for _, ch := range html.EscapeString(fc.Text) {
+		switch ch {
+		case '{':
+			b.WriteString(`{ "{" }`)
+		case '}':
+			b.WriteString(`{ "}" }`)
+		default:
+			b.WriteRune(ch)
+		}
+	}
+	return b.String()
+}
+
+// templAttr writes Alpine shorthands in their long form, which templ
+// accepts as attribute names.
+func templAttr(fc *funcCall, att html.Attribute) string {
+	key := expandAlpineKey(att.Key)
+	if expr, ok := fc.AttrExprs[att.Key]; ok {
+		return fmt.Sprintf("%s={ %s }", key, expr)
+	}
+	if len(att.Val) == 0 {
+		return key
+	}
+	return fmt.Sprintf(`%s="%s"`, key, strings.ReplaceAll(att.Val, `"`, "&quot;"))
+}
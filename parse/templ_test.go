@@ -0,0 +1,87 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestTemplTarget(t *testing.T) {
+	var cases = []struct {
+		name         string
+		config       *parse.Config
+		placeholders bool
+		html         string
+		templ        string
+	}{
+		{
+			name:   "markup, alpine and escaping",
+			config: &parse.Config{Target: parse.TargetTempl, Name: "Dropdown"},
+			html: `
+<div class="dropdown" x-data="{open: false}" @click.outside="open = false" hx-get="/items">
+  <button :class="{'active': open}" @click="open = !open">Total {price}</button>
+  <p>if you like it</p>
+  <img src="a.png" alt="A &quot;quoted&quot; name">
+  <ul x-show="open"><li>One</li></ul>
+</div>
+`,
+			templ: `package hello
+
+templ Dropdown() {
+	<div class="dropdown" x-data="{open: false}" x-on:click.outside="open = false" hx-get="/items">
+		<button x-bind:class="{'active': open}" x-on:click="open = !open">Total { "{" }price{ "}" }</button>
+		<p>{ "if you like it" }</p>
+		<img src="a.png" alt="A &quot;quoted&quot; name"/>
+		<ul x-show="open">
+			<li>One</li>
+		</ul>
+	</div>
+}
+`,
+		},
+		{
+			name:         "placeholders",
+			config:       &parse.Config{Target: parse.TargetTempl, Name: "Card"},
+			placeholders: true,
+			html: `
+<div class="card {{ kind }}">
+  <h2>{{ title }}</h2>
+  {% for tag in tags %}<span>{{ tag }}</span>{% endfor %}
+  {% if featured %}<footer>Featured</footer>{% endif %}
+</div>
+`,
+			templ: `package hello
+
+templ Card(kind string, title string, tags []string, featured bool) {
+	<div class={ "card " + kind }>
+		<h2>{ title }</h2>
+		for _, tag := range tags {
+			<span>{ tag }</span>
+		}
+		if featured {
+			<footer>Featured</footer>
+		}
+	</div>
+}
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var templ string
+			if c.placeholders {
+				templ = parse.GenerateHTMLGoFromPlaceholders("", false, c.config, strings.NewReader(c.html))
+			} else {
+				templ = parse.GenerateHTMLGoWithConfig("", false, c.config, strings.NewReader(c.html))
+			}
+			diff := testingutils.PrettyJsonDiff(c.templ, templ)
+
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
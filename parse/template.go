@@ -0,0 +1,491 @@
+package parse
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	tparse "text/template/parse"
+
+	"github.com/iancoleman/strcase"
+	"golang.org/x/net/html"
+)
+
+// GenerateHTMLGoFromTemplate converts a html/template file into htmlgo code.
+// {{.Field}} becomes data.Field, {{if}} becomes If(...).Else(...) and {{range}}
+// becomes a loop appending to a HTMLComponents slice.
+// With the func and method shapes the template data is the parameter data,
+// typed by the "data" entry of cfg.Params.
+func GenerateHTMLGoFromTemplate(pkg string, childrenMode bool, cfg *Config, tmpl io.Reader) string {
+	return mustGenerate(newConverter(pkg, childrenMode, cfg).ConvertTemplate(tmpl))
+}
+
+// ConvertTemplate is GenerateHTMLGoFromTemplate with the options of c.
+func (c *Converter) ConvertTemplate(tmpl io.Reader) (r string, err error) {
+	if err = c.validate(); err != nil {
+		return
+	}
+	src, err := ioutil.ReadAll(tmpl)
+	if err != nil {
+		return
+	}
+
+	t := tparse.New("html2go")
+	t.Mode = tparse.SkipFuncCheck
+	trees := map[string]*tparse.Tree{}
+	_, err = t.Parse(string(src), "", "", trees)
+	if err != nil {
+		return
+	}
+	var defined []string
+	for name := range trees {
+		if name != t.Name {
+			defined = append(defined, name)
+		}
+	}
+	if len(defined) > 0 {
+		sort.Strings(defined)
+		for _, m := range blockAction.FindAllStringSubmatch(string(src), -1) {
+			if name, _ := strconv.Unquote(m[1]); name == defined[0] {
+				err = fmt.Errorf("{{block %q}} isn't supported, write its content in place of it", name)
+				return
+			}
+		}
+		err = fmt.Errorf("{{define %q}} isn't supported, convert each defined template from a file of its own", defined[0])
+		return
+	}
+
+	tc := &templateConverter{
+		html: &strings.Builder{},
+		dots: []string{"data"},
+		vars: map[string]string{},
+		used: map[string]bool{},
+		keys: map[string]bool{},
+	}
+	tc.list(t.Root)
+	if tc.err != nil {
+		err = tc.err
+		return
+	}
+
+	body, err := parseBody(strings.NewReader(tc.html.String()))
+	if err != nil {
+		return
+	}
+	cfg := c.opts.Config
+	fc := convert(body, c.methodNames, tc.exprs, cfg.Whitespace)
+	fc.Params = append(fc.Params, &funcParam{Name: "data", Type: "Data"})
+	return generate(fc, c.methodNames, c.opts.Pkg, c.opts.ChildrenMode, &cfg, ShapeVar)
+}
+
+var exprPlaceholder = regexp.MustCompile(`__html2go_(\d+)__`)
+
+// blockAction matches the start of {{block "name" pipeline}}, which the
+// parser turns into a {{define}} and a {{template}}.
+var blockAction = regexp.MustCompile(`\{\{-?\s*block\s+("(?:[^"\\]|\\.)*")`)
+
+func placeholder(i int) string {
+	return fmt.Sprintf("__html2go_%d__", i)
+}
+
+// templateConverter writes the template back as HTML, replacing actions by
+// placeholders and control structures by annotated <template> elements,
+// which the HTML parser accepts anywhere, including inside tables.
+type templateConverter struct {
+	html  *strings.Builder
+	exprs []string
+	dots  []string
+	vars  map[string]string
+	used  map[string]bool
+	// keys are the variables of range keys
+	keys  map[string]bool
+	items int
+
+	inTag bool
+	quote byte
+	// last is the last character of the tag outside of attribute values
+	last byte
+	// err is the first expression that can't be converted
+	err error
+}
+
+func (tc *templateConverter) dot() string {
+	return tc.dots[len(tc.dots)-1]
+}
+
+func (tc *templateConverter) write(s string) {
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case !tc.inTag:
+			if ch == '<' && i+1 < len(s) && (isLetter(s[i+1]) || s[i+1] == '/') {
+				tc.inTag = true
+				tc.last = ch
+			}
+		case tc.quote != 0:
+			if ch == tc.quote {
+				tc.quote = 0
+				tc.last = ch
+			}
+		case ch == '"' || ch == '\'':
+			tc.quote = ch
+		case ch == '>':
+			tc.inTag = false
+		case ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r':
+			tc.last = ch
+		}
+	}
+	tc.html.WriteString(s)
+}
+
+// inName reports whether the HTML written so far ends inside a tag where a
+// tag or attribute name goes, which can't be an expression.
+func (tc *templateConverter) inName() bool {
+	return tc.inTag && tc.quote == 0 && tc.last != '='
+}
+
+// failInName sets the error of src, the source of an expression, if it is
+// where a tag or attribute name goes.
+func (tc *templateConverter) failInName(src string) bool {
+	if !tc.inName() {
+		return false
+	}
+	if tc.err == nil {
+		tc.err = fmt.Errorf("%s: only attribute values and text can be expressions, not tag or attribute names", src)
+	}
+	return true
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func (tc *templateConverter) writeExpr(expr string) {
+	tc.exprs = append(tc.exprs, expr)
+	tc.write(placeholder(len(tc.exprs) - 1))
+}
+
+func (tc *templateConverter) open(attrs ...string) {
+	tc.write(openTemplate(attrs...))
+}
+
+func openTemplate(attrs ...string) string {
+	var b strings.Builder
+	b.WriteString("<template")
+	for i := 0; i+1 < len(attrs); i += 2 {
+		_, _ = fmt.Fprintf(&b, ` %s="%s"`, attrs[i], html.EscapeString(attrs[i+1]))
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+func (tc *templateConverter) list(l *tparse.ListNode) {
+	if l == nil {
+		return
+	}
+	for _, n := range l.Nodes {
+		tc.node(n)
+	}
+}
+
+func (tc *templateConverter) node(n tparse.Node) {
+	switch n := n.(type) {
+	case *tparse.TextNode:
+		tc.write(string(n.Text))
+	case *tparse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			tc.declare(n.Pipe)
+			return
+		}
+		if tc.failInName(n.String()) {
+			return
+		}
+		expr := tc.pipe(n.Pipe)
+		if tc.isNumeric(n.Pipe) {
+			expr = fmt.Sprintf("fmt.Sprint(%s)", expr)
+		}
+		tc.writeExpr(expr)
+	case *tparse.IfNode:
+		if tc.inTag {
+			if tc.failInName(n.String()) {
+				return
+			}
+			tc.writeExpr(tc.stringExpr(n))
+			return
+		}
+		tc.branch(annotationIf, tc.pipe(n.Pipe), n.List, n.ElseList)
+	case *tparse.WithNode:
+		// the value is tested like the template does, and the content is
+		// only built if it holds, as it may dereference a nil pointer
+		expr := tc.pipe(n.Pipe)
+		tc.open(annotationIf, fmt.Sprintf("func() bool { ok, _ := template.IsTrue(%s); return ok }()", expr),
+			annotationLazy, "")
+		tc.dots = append(tc.dots, expr)
+		tc.list(n.List)
+		tc.dots = tc.dots[:len(tc.dots)-1]
+		if n.ElseList != nil {
+			tc.open(annotationElse, "")
+			tc.list(n.ElseList)
+			tc.write("</template>")
+		}
+		tc.write("</template>")
+	case *tparse.RangeNode:
+		tc.rangeNode(n)
+	case *tparse.TemplateNode:
+		arg := "nil"
+		if n.Pipe != nil {
+			arg = tc.pipe(n.Pipe)
+		}
+		tc.open(annotationExpr, fmt.Sprintf("%s(%s)", strcase.ToCamel(n.Name), arg))
+		tc.write("</template>")
+	}
+}
+
+func (tc *templateConverter) branch(key string, expr string, list, elseList *tparse.ListNode) {
+	tc.open(key, expr)
+	tc.list(list)
+	if elseList != nil {
+		tc.open(annotationElse, "")
+		tc.list(elseList)
+		tc.write("</template>")
+	}
+	tc.write("</template>")
+}
+
+func (tc *templateConverter) rangeNode(n *tparse.RangeNode) {
+	expr := tc.pipe(n.Pipe)
+	var key, value string
+	switch len(n.Pipe.Decl) {
+	case 1:
+		value = goVar(n.Pipe.Decl[0].Ident[0])
+	case 2:
+		key = goVar(n.Pipe.Decl[0].Ident[0])
+		value = goVar(n.Pipe.Decl[1].Ident[0])
+	}
+	if len(value) == 0 {
+		tc.items++
+		value = "item"
+		if tc.items > 1 {
+			value = fmt.Sprintf("item%d", tc.items)
+		}
+	}
+
+	if len(key) > 0 {
+		tc.keys[key] = true
+	}
+	// The body is converted first so that an unused key can become _.
+	out := tc.html
+	tc.html = &strings.Builder{}
+	tc.dots = append(tc.dots, value)
+	tc.list(n.List)
+	tc.dots = tc.dots[:len(tc.dots)-1]
+	body := tc.html.String()
+	tc.html = out
+
+	if !tc.used[key] {
+		key = ""
+	}
+	tc.html.WriteString(openTemplate(annotationRange, expr, annotationRangeKey, key, annotationRangeValue, value))
+	tc.html.WriteString(body)
+	if n.ElseList != nil {
+		tc.open(annotationElse, "")
+		tc.list(n.ElseList)
+		tc.write("</template>")
+	}
+	tc.write("</template>")
+}
+
+// declare remembers {{$x := pipeline}} so later uses of $x are replaced by
+// the pipeline expression.
+func (tc *templateConverter) declare(p *tparse.PipeNode) {
+	expr := tc.pipe(&tparse.PipeNode{Cmds: p.Cmds})
+	for _, d := range p.Decl {
+		tc.vars[d.Ident[0]] = expr
+	}
+}
+
+// stringExpr converts an {{if}} inside a tag into a Go string expression.
+func (tc *templateConverter) stringExpr(n *tparse.IfNode) string {
+	listExpr := func(l *tparse.ListNode) string {
+		if l == nil {
+			return `""`
+		}
+		var parts []string
+		for _, c := range l.Nodes {
+			switch c := c.(type) {
+			case *tparse.TextNode:
+				parts = append(parts, strconv.Quote(string(c.Text)))
+			case *tparse.ActionNode:
+				parts = append(parts, tc.pipe(c.Pipe))
+			case *tparse.IfNode:
+				parts = append(parts, tc.stringExpr(c))
+			}
+		}
+		if len(parts) == 0 {
+			return `""`
+		}
+		return strings.Join(parts, " + ")
+	}
+	return fmt.Sprintf("func() string {\nif %s {\nreturn %s\n}\nreturn %s\n}()",
+		tc.pipe(n.Pipe), listExpr(n.List), listExpr(n.ElseList))
+}
+
+func (tc *templateConverter) pipe(p *tparse.PipeNode) (r string) {
+	for i, c := range p.Cmds {
+		var final []string
+		if i > 0 {
+			final = append(final, r)
+		}
+		r = tc.command(c, final)
+	}
+	return
+}
+
+func (tc *templateConverter) command(c *tparse.CommandNode, final []string) string {
+	ident, ok := c.Args[0].(*tparse.IdentifierNode)
+	if !ok {
+		operand := tc.arg(c.Args[0])
+		if len(c.Args) == 1 && len(final) == 0 {
+			return operand
+		}
+		return fmt.Sprintf("%s(%s)", operand, strings.Join(append(tc.args(c.Args[1:]), final...), ", "))
+	}
+
+	args := append(tc.args(c.Args[1:]), final...)
+	switch ident.Ident {
+	case "eq", "ne", "lt", "le", "gt", "ge":
+		op := map[string]string{"eq": "==", "ne": "!=", "lt": "<", "le": "<=", "gt": ">", "ge": ">="}[ident.Ident]
+		var conds []string
+		for _, a := range args[1:] {
+			conds = append(conds, fmt.Sprintf("%s %s %s", args[0], op, a))
+		}
+		return strings.Join(conds, " || ")
+	case "and":
+		return strings.Join(args, " && ")
+	case "or":
+		return strings.Join(args, " || ")
+	case "not":
+		return "!" + args[0]
+	case "index":
+		r := args[0]
+		for _, a := range args[1:] {
+			r += "[" + a + "]"
+		}
+		return r
+	case "call":
+		return fmt.Sprintf("%s(%s)", args[0], strings.Join(args[1:], ", "))
+	case "print":
+		return fmt.Sprintf("fmt.Sprint(%s)", strings.Join(args, ", "))
+	case "printf":
+		return fmt.Sprintf("fmt.Sprintf(%s)", strings.Join(args, ", "))
+	case "println":
+		return fmt.Sprintf("fmt.Sprintln(%s)", strings.Join(args, ", "))
+	}
+	return fmt.Sprintf("%s(%s)", ident.Ident, strings.Join(args, ", "))
+}
+
+func (tc *templateConverter) args(nodes []tparse.Node) (r []string) {
+	for _, n := range nodes {
+		r = append(r, tc.arg(n))
+	}
+	return
+}
+
+func (tc *templateConverter) arg(n tparse.Node) string {
+	switch n := n.(type) {
+	case *tparse.DotNode:
+		return tc.dot()
+	case *tparse.FieldNode:
+		return tc.dot() + "." + strings.Join(n.Ident, ".")
+	case *tparse.VariableNode:
+		v, ok := tc.vars[n.Ident[0]]
+		if !ok {
+			v = goVar(n.Ident[0])
+			tc.used[v] = true
+		}
+		return strings.Join(append([]string{v}, n.Ident[1:]...), ".")
+	case *tparse.ChainNode:
+		return tc.arg(n.Node) + "." + strings.Join(n.Field, ".")
+	case *tparse.PipeNode:
+		return "(" + tc.pipe(n) + ")"
+	case *tparse.IdentifierNode:
+		return n.Ident + "()"
+	case *tparse.StringNode:
+		return strconv.Quote(n.Text)
+	case *tparse.NumberNode, *tparse.BoolNode, *tparse.NilNode:
+		return n.String()
+	}
+	return n.String()
+}
+
+// isNumeric reports whether the pipeline may produce a number, which needs
+// formatting before it can be used as text, like len or the key of a range.
+func (tc *templateConverter) isNumeric(p *tparse.PipeNode) bool {
+	last := p.Cmds[len(p.Cmds)-1]
+	if ident, ok := last.Args[0].(*tparse.IdentifierNode); ok {
+		return ident.Ident == "len"
+	}
+	if v, ok := last.Args[0].(*tparse.VariableNode); ok && len(last.Args) == 1 && len(v.Ident) == 1 {
+		_, declared := tc.vars[v.Ident[0]]
+		return !declared && tc.keys[goVar(v.Ident[0])]
+	}
+	_, ok := last.Args[0].(*tparse.NumberNode)
+	return ok && len(last.Args) == 1
+}
+
+func goVar(name string) string {
+	if name == "$" {
+		return "data"
+	}
+	return strings.TrimPrefix(name, "$")
+}
+
+// expandTemplateExprs replaces the placeholders left in texts and attribute
+// values by the Go expressions they stand for.
+func expandTemplateExprs(fc *funcCall, exprs []string) {
+	if len(fc.Text) > 0 {
+		if expr, ok := concatExprs(fc.Text, exprs); ok {
+			fc.TextExpr = expr
+		}
+	}
+	for _, att := range fc.Attrs {
+		expr, ok := concatExprs(att.Val, exprs)
+		if !ok {
+			continue
+		}
+		if fc.AttrExprs == nil {
+			fc.AttrExprs = map[string]string{}
+		}
+		fc.AttrExprs[att.Key] = expr
+	}
+	for _, c := range fc.Children {
+		expandTemplateExprs(c, exprs)
+	}
+	for _, c := range fc.Else {
+		expandTemplateExprs(c, exprs)
+	}
+}
+
+func concatExprs(s string, exprs []string) (r string, ok bool) {
+	locs := exprPlaceholder.FindAllStringSubmatchIndex(s, -1)
+	if len(locs) == 0 {
+		return
+	}
+	var parts []string
+	last := 0
+	for _, loc := range locs {
+		if loc[0] > last {
+			parts = append(parts, strconv.Quote(s[last:loc[0]]))
+		}
+		i, _ := strconv.Atoi(s[loc[2]:loc[3]])
+		parts = append(parts, exprs[i])
+		last = loc[1]
+	}
+	if last < len(s) {
+		parts = append(parts, strconv.Quote(s[last:]))
+	}
+	return strings.Join(parts, " + "), true
+}
@@ -0,0 +1,208 @@
+package parse_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestGenerateHTMLGoFromTemplate(t *testing.T) {
+	var cases = []struct {
+		name   string
+		pkg    string
+		tmpl   string
+		gocode string
+	}{
+		{
+			name: "fields, if and range",
+			tmpl: `
+<div class="page {{.Theme}}">
+  <h1>{{.Title}}</h1>
+  <p>Hello {{.User.Name}}!</p>
+  {{if .LoggedIn}}<a href="/logout">Logout</a>{{else}}<a href="/login">Login</a>{{end}}
+  <ul>{{range .Items}}<li class="{{if .Active}}on{{end}}">{{.Name}}</li>{{end}}</ul>
+</div>
+`,
+			gocode: `package hello
+
+var n = Body(
+	Div(
+		H1(data.Title),
+		P(
+			Text("Hello "+data.User.Name+"!"),
+		),
+		If(data.LoggedIn,
+			A(
+				Text("Logout"),
+			).Href("/logout"),
+		).
+			Else(
+				A(
+					Text("Login"),
+				).Href("/login"),
+			),
+		Ul(
+			func() (r HTMLComponents) {
+				for _, item := range data.Items {
+					r = append(r,
+						Li(
+							Text(item.Name),
+						).Class(func() string {
+							if item.Active {
+								return "on"
+							}
+							return ""
+						}()),
+					)
+				}
+				return
+			}(),
+		),
+	).Class("page " + data.Theme),
+)
+`,
+		},
+		{
+			name: "range with variables and else inside table",
+			pkg:  "h",
+			tmpl: `
+<table>
+  {{range $i, $row := .Rows}}<tr><td>{{$i}}</td><td>{{$row.Name}}</td><td>{{len $row.Tags}}</td></tr>{{else}}<tr><td>None</td></tr>{{end}}
+</table>
+`,
+			gocode: `package hello
+
+var n = h.Body(
+	h.Table(
+		func() (r h.HTMLComponents) {
+			for i, row := range data.Rows {
+				r = append(r,
+					h.Tr(
+						h.Td(
+							h.Text(fmt.Sprint(i)),
+						),
+						h.Td(
+							h.Text(row.Name),
+						),
+						h.Td(
+							h.Text(fmt.Sprint(len(row.Tags))),
+						),
+					),
+				)
+			}
+			if len(r) == 0 {
+				r = append(r,
+					h.Tr(
+						h.Td(
+							h.Text("None"),
+						),
+					),
+				)
+			}
+			return
+		}(),
+	),
+)
+`,
+		},
+		{
+			name: "with and range keys",
+			tmpl: `{{with .User}}<span>{{.Name}}</span>{{else}}<a href="/login">Login</a>{{end}}` +
+				`{{range $i, $u := .Users}}<p>{{$i}} {{$u.Name}}</p>{{end}}`,
+			gocode: `package hello
+
+var n = Body(
+	func() (r HTMLComponents) {
+		if func() bool { ok, _ := template.IsTrue(data.User); return ok }() {
+			r = append(r,
+				Span(data.User.Name),
+			)
+		} else {
+			r = append(r,
+				A(
+					Text("Login"),
+				).Href("/login"),
+			)
+		}
+		return
+	}(),
+	func() (r HTMLComponents) {
+		for i, u := range data.Users {
+			r = append(r,
+				P(
+					Text(fmt.Sprint(i)+" "+u.Name),
+				),
+			)
+		}
+		return
+	}(),
+)
+`,
+		},
+		{
+			name: "leading range",
+			tmpl: `{{range .Items}}<li>{{.}}</li>{{end}}`,
+			gocode: `package hello
+
+var n = Body(
+	func() (r HTMLComponents) {
+		for _, item := range data.Items {
+			r = append(r,
+				Li(
+					Text(item),
+				),
+			)
+		}
+		return
+	}(),
+)
+`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gocode := parse.GenerateHTMLGoFromTemplate(c.pkg, false, nil, strings.NewReader(c.tmpl))
+			diff := testingutils.PrettyJsonDiff(c.gocode, gocode)
+
+			if len(diff) > 0 {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestConvertTemplateErrors(t *testing.T) {
+	cases := map[string]string{
+		"attribute name":  `<div {{if .Hidden}}hidden{{end}}>x</div>`,
+		"attributes":      `<input {{.Attrs}}>`,
+		"tag name":        `<h{{.Level}}>x</h1>`,
+		"define":          `{{define "item"}}<li>{{.}}</li>{{end}}<ul>{{template "item" .}}</ul>`,
+		"block":           `<ul>{{block "item" .}}<li>{{.}}</li>{{end}}</ul>`,
+		"after attribute": `<a href="/" {{.Attrs}}>x</a>`,
+	}
+	for name, tmpl := range cases {
+		t.Run(name, func(t *testing.T) {
+			code, err := parse.NewConverter(parse.Options{}).ConvertTemplate(strings.NewReader(tmpl))
+			if err == nil {
+				t.Errorf("no error, generated %s", code)
+			}
+		})
+	}
+
+	_, err := parse.NewConverter(parse.Options{}).ConvertTemplate(strings.NewReader(cases["block"]))
+	if err == nil || !strings.Contains(err.Error(), `{{block "item"}}`) {
+		t.Errorf("error of a block: %v", err)
+	}
+
+	code, err := parse.NewConverter(parse.Options{Config: parse.Config{Shape: parse.ShapeExpr}}).
+		ConvertTemplate(strings.NewReader(`<a href={{.URL}} title="{{if .T}}t{{end}}">x</a>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(code, `Href(data.URL)`) {
+		t.Errorf("unquoted attribute value: %s", code)
+	}
+}
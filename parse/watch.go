@@ -0,0 +1,287 @@
+package parse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Watcher regenerates the Go file next to every HTML file of a directory
+// tree when the HTML changes, header.html into the function Header of
+// header.go, in the package of the directory. It polls the modification
+// times, so it needs no file system notifications. Go files that don't start
+// with the "Code generated by html2go" header are never overwritten.
+type Watcher struct {
+	Converter *Converter
+	Dir       string
+	// Interval between two polls, half a second if zero.
+	Interval time.Duration
+	// Debounce is how long a file must be unchanged before it is converted,
+	// so that rapid saves convert it once, 300ms if zero.
+	Debounce time.Duration
+	// Log receives the generated files and the diagnostics, warnings and
+	// errors of every file.
+	Log io.Writer
+
+	polled  bool
+	seen    map[string]fileState
+	pending map[string]time.Time
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Run polls until ctx is done. It returns the error of the options of the
+// Converter right away.
+func (w *Watcher) Run(ctx context.Context) error {
+	if err := w.Converter.validate(); err != nil {
+		return err
+	}
+	interval := w.Interval
+	if interval == 0 {
+		interval = 500 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := w.Poll(time.Now())
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll converts the HTML files that changed and have been left alone for the
+// debounce time by now. The first poll converts the files whose Go file is
+// missing or older. Run calls it every Interval.
+func (w *Watcher) Poll(now time.Time) (err error) {
+	if w.seen == nil {
+		w.seen = map[string]fileState{}
+		w.pending = map[string]time.Time{}
+	}
+	debounce := w.Debounce
+	if debounce == 0 {
+		debounce = 300 * time.Millisecond
+	}
+
+	err = filepath.Walk(w.Dir, func(path string, info os.FileInfo, err error) error {
+		// files removed while walking, like editor backups, don't end
+		// watching, only a missing directory does
+		if err != nil && path != w.Dir {
+			w.log("%s: %v\n", path, err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), ".html") {
+			return nil
+		}
+		st := fileState{modTime: info.ModTime(), size: info.Size()}
+		if old, ok := w.seen[path]; ok && old == st {
+			return nil
+		}
+		w.seen[path] = st
+		if !w.polled && w.upToDate(path, info) {
+			return nil
+		}
+		w.pending[path] = now
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	w.polled = true
+
+	var paths []string
+	for path, changed := range w.pending {
+		if now.Sub(changed) >= debounce {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		delete(w.pending, path)
+		w.regenerate(path)
+	}
+	return
+}
+
+func (w *Watcher) upToDate(path string, info os.FileInfo) bool {
+	out, err := os.Stat(w.outputPath(path))
+	return err == nil && !out.ModTime().Before(info.ModTime())
+}
+
+func (w *Watcher) outputPath(path string) string {
+	ext := ".go"
+	if w.Converter.opts.Target == TargetTempl {
+		ext = ".templ"
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ext
+}
+
+func (w *Watcher) log(format string, a ...interface{}) {
+	if w.Log != nil {
+		_, _ = fmt.Fprintf(w.Log, format, a...)
+	}
+}
+
+// regenerate converts the HTML file at path, logging instead of returning
+// errors so that watching goes on.
+func (w *Watcher) regenerate(path string) {
+	defer func() {
+		if r := recover(); r != nil {
+			w.log("%s: %v\n", path, r)
+		}
+	}()
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		w.log("%s: %v\n", path, err)
+		return
+	}
+	ds, err := Lint(bytes.NewReader(src))
+	if err != nil {
+		w.log("%s: %v\n", path, err)
+		return
+	}
+	for _, d := range ds {
+		w.log("%s:%s\n", path, d)
+	}
+
+	warnings := bytes.NewBuffer(nil)
+	c := *w.Converter
+	c.opts.Warnings = warnings
+	if len(c.opts.Name) == 0 {
+		c.opts.Name = componentName(path, c.opts.Target, c.opts.Pkg)
+	}
+	c.opts.component = true
+	if len(c.opts.Shape) == 0 {
+		c.opts.Shape = ShapeFunc
+	}
+	code, err := c.Convert(bytes.NewReader(src))
+	s := bufio.NewScanner(warnings)
+	for s.Scan() {
+		w.log("%s: %s\n", path, s.Text())
+	}
+	if err != nil {
+		w.log("%s: %v\n", path, err)
+		return
+	}
+
+	out := w.outputPath(path)
+	generated, err := generatedFile(out)
+	if err != nil {
+		w.log("%s: %v\n", path, err)
+		return
+	}
+	if !generated {
+		w.log("%s: %s isn't generated by html2go, not overwriting it\n", path, out)
+		return
+	}
+	code, err = c.goFile(code, filepath.Base(path), packageName(filepath.Dir(path), out))
+	if err != nil {
+		w.log("%s: %v\n", path, err)
+		return
+	}
+	err = ioutil.WriteFile(out, []byte(code), 0644)
+	if err != nil {
+		w.log("%s: %v\n", path, err)
+		return
+	}
+	w.log("%s -> %s\n", path, out)
+}
+
+var generatedHeader = regexp.MustCompile(`^// Code generated by html2go .*\bDO NOT EDIT\.$`)
+
+// generatedFile reports whether the file at path is missing or has the
+// header goFile writes before its package clause.
+func generatedFile(path string) (ok bool, err error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if generatedHeader.MatchString(line) {
+			return true, nil
+		}
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+	}
+	return false, s.Err()
+}
+
+// goFile turns the generated code of package hello into a file of the
+// package pkgName, with the imports of the target.
+func (c *Converter) goFile(code string, source string, pkgName string) (r string, err error) {
+	header := fmt.Sprintf("// Code generated by html2go from %s. DO NOT EDIT.\n\npackage %s\n", source, pkgName)
+	if c.opts.Target == TargetTempl {
+		return header + strings.TrimPrefix(code, "package hello\n"), nil
+	}
+	header += "\n" + getBackend(c.opts.Target).imports(c.opts.Pkg)
+	b, err := format.Source([]byte(header + strings.TrimPrefix(code, "package hello\n")))
+	r = string(b)
+	return
+}
+
+// componentName is the function generated from the HTML file at path, named
+// after it, e.g. card.html is Card. Names the dot-imported package already
+// declares, like Header of htmlgo, get the prefix Render.
+func componentName(path string, target string, pkg string) string {
+	name := strcase.ToCamel(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if len(pkg) > 0 || target == TargetTempl {
+		return name
+	}
+	if bk, err := findBackend(target); err == nil && bk.declares(name) {
+		return "Render" + name
+	}
+	return name
+}
+
+// packageName is the package of the other Go files in dir, or the name of
+// dir.
+func packageName(dir string, skip string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, file := range files {
+		if file == skip || strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+		if err == nil {
+			return f.Name.Name
+		}
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "main"
+	}
+	return strings.ToLower(strcase.ToLowerCamel(filepath.Base(abs)))
+}
@@ -0,0 +1,179 @@
+package parse_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunfmin/html2go/parse"
+	"github.com/theplant/testingutils"
+)
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "html2go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, content string) {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("doc.go", "package site\n")
+	write("site-header.html", `<header><h1>Hi</h1></header>`)
+
+	log := bytes.NewBuffer(nil)
+	w := &parse.Watcher{
+		Converter: parse.NewConverter(parse.Options{}),
+		Dir:       dir,
+		Debounce:  time.Second,
+		Log:       log,
+	}
+	start := time.Now()
+	poll := func(after time.Duration) {
+		err := w.Poll(start.Add(after))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := filepath.Join(dir, "site-header.go")
+	poll(0)
+	if _, err := os.Stat(out); err == nil {
+		t.Fatal("converted before the debounce time")
+	}
+	poll(time.Second)
+
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `// Code generated by html2go from site-header.html. DO NOT EDIT.
+
+package site
+
+import . "github.com/theplant/htmlgo"
+
+func SiteHeader() HTMLComponent {
+	return Header(
+		H1("Hi"),
+	)
+}
+`
+	diff := testingutils.PrettyJsonDiff(expected, string(b))
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+
+	// broken markup is reported, and watching goes on
+	write("site-header.html", `<header><h1>Hi</header>`)
+	poll(2 * time.Second)
+	write("broken.html", `<template data-html2go-if="a &&"></template>`)
+	poll(2 * time.Second)
+	poll(3 * time.Second)
+
+	// hand-written Go files are kept
+	handWritten := "package site\n\nfunc Footer() string { return \"(c)\" }\n"
+	write("footer.go", handWritten)
+	write("footer.html", `<footer>(c)</footer>`)
+	poll(4 * time.Second)
+	poll(5 * time.Second)
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "footer.go")); string(b) != handWritten {
+		t.Errorf("hand-written file overwritten: %s", b)
+	}
+
+	expectedLog := []string{
+		filepath.Join(dir, "site-header.html") + " -> " + out,
+		filepath.Join(dir, "broken.html") + `: invalid Go expression "a &&": 1:5: expected operand, found 'EOF'`,
+		filepath.Join(dir, "site-header.html") + ":1:15: </header> closes the unclosed <h1> from 1:9",
+		filepath.Join(dir, "site-header.html") + " -> " + out,
+		filepath.Join(dir, "footer.html") + ": " + filepath.Join(dir, "footer.go") + " isn't generated by html2go, not overwriting it",
+	}
+	diff = testingutils.PrettyJsonDiff(strings.Join(expectedLog, "\n")+"\n", log.String())
+	if len(diff) > 0 {
+		t.Error(diff)
+	}
+}
+
+func TestWatcherCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a package")
+	}
+	// inside the module to build with its htmlgo
+	dir, err := os.MkdirTemp(".", "_watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"doc.go":      "package site\n",
+		"header.html": `<header><h1>Hi</h1></header>`,
+		"nav.html":    `<nav><a href="/">Home</a></nav><p>Menu</p>`,
+		"card.html":   `<div class="card">Card</div>`,
+	}
+	for name, content := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := &parse.Watcher{Converter: parse.NewConverter(parse.Options{}), Dir: dir, Debounce: time.Second}
+	start := time.Now()
+	for _, after := range []time.Duration{0, time.Second} {
+		err = w.Poll(start.Add(after))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]string{
+		"header.go": `func RenderHeader() HTMLComponent {
+	return Header(
+		H1("Hi"),
+	)
+}`,
+		"nav.go": `func RenderNav() HTMLComponent {
+	return Components(
+		Nav(
+			A(
+				Text("Home"),
+			).Href("/"),
+		),
+		P(
+			Text("Menu"),
+		),
+	)
+}`,
+		"card.go": `func Card() HTMLComponent {
+	return Div(
+		Text("Card"),
+	).Class("card")
+}`,
+	}
+	for name, code := range expected {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := string(b)
+		got = strings.TrimSpace(got[strings.Index(got, "func "):])
+		diff := testingutils.PrettyJsonDiff(code, got)
+		if len(diff) > 0 {
+			t.Error(diff)
+		}
+	}
+
+	out, err := exec.Command("go", "vet", "./"+dir).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}